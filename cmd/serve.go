@@ -7,18 +7,30 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization/union"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/breakglass"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/config"
+	breakglasshandler "github.com/platform-mesh/rebac-authz-webhook/pkg/handler/breakglass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/bypass"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/contextual"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/impersonation"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/nonresourceattributes"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/orgs"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/policy"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/scopes"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/orgsstore"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/retry"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -80,10 +92,21 @@ func NewServeCmd() *cobra.Command {
 				klog.Exit(err, "unable to set up overall controller manager")
 			}
 
-			clusterCache, err := clustercache.New(mgr)
+			typeNaming, err := util.ParseTypeNaming(serverCfg.Webhook.TypeNaming)
+			if err != nil {
+				klog.Exit(err, "invalid type naming")
+			}
+
+			clusterCacheOpts := []clustercache.Option{clustercache.WithStoreResync(serverCfg.Webhook.StoreResyncInterval)}
+			if serverCfg.Webhook.DetectTypeCollisions {
+				clusterCacheOpts = append(clusterCacheOpts, clustercache.WithTypeCollisionDetection(typeNaming, util.MaxRelationLength))
+			}
+
+			clusterCache, err := clustercache.New(mgr, clusterCacheOpts...)
 			if err != nil {
 				klog.Exit(err, "failed to create cluster cache")
 			}
+			clusters := clustercache.NewResolver(clusterCache, append([]string{serverCfg.Webhook.ClusterKey}, serverCfg.Webhook.FallbackClusterKeys...)...)
 
 			conn, err := grpc.NewClient(serverCfg.OpenFGAAddr,
 				grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -95,25 +118,142 @@ func NewServeCmd() *cobra.Command {
 			defer conn.Close() //nolint:errcheck
 
 			fga := openfgav1.NewOpenFGAServiceClient(conn)
+			models := openfga.NewModelCache(fga, serverCfg.OpenFGAModelRefreshInterval, serverCfg.OpenFGAAuthorizationModelIDs)
+
+			consistency, err := openfga.ParseConsistencyPolicy(serverCfg.OpenFGAConsistency)
+			if err != nil {
+				klog.Exit(err, "invalid OpenFGA consistency configuration")
+			}
+
+			if serverCfg.OpenFGADenyRelationFormat != "" {
+				if err := openfga.ValidateDenyRelationFormat(serverCfg.OpenFGADenyRelationFormat); err != nil {
+					klog.Exit(err, "invalid OpenFGA deny relation format")
+				}
+			}
+
+			var checkContext *openfga.ContextBuilder
+			if serverCfg.OpenFGACheckContext {
+				checkContext = openfga.NewContextBuilder(serverCfg.OpenFGACheckContextExtraKeys...)
+			}
 
-			storeRes, err := fga.ListStores(ctx, &openfgav1.ListStoresRequest{Name: "orgs"})
+			groups, err := identity.NewGroupFilter(serverCfg.Webhook.GroupsInclude, serverCfg.Webhook.GroupsExclude)
 			if err != nil {
-				klog.Exit(err, "cannot list stores from OpenFGA")
+				klog.Exit(err, "invalid group patterns")
 			}
-			if len(storeRes.Stores) == 0 {
-				klog.Exit("no stores found in OpenFGA")
+
+			identities, err := identity.NewMapper(identity.MapperConfig{
+				Source:             serverCfg.Identity.Source,
+				PrefixReplacements: serverCfg.Identity.PrefixReplacements,
+				Lowercase:          serverCfg.Identity.Lowercase,
+				Types:              serverCfg.Identity.Types,
+			})
+			if err != nil {
+				klog.Exit(err, "invalid identity configuration")
+			}
+
+			unknownVerbPolicy, err := contextual.ParseUnknownVerbPolicy(serverCfg.Webhook.UnknownVerbPolicy)
+			if err != nil {
+				klog.Exit(err, "invalid unknown verb policy")
+			}
+
+			permissionClaims, err := contextual.ParsePermissionClaimPolicy(serverCfg.Webhook.BindPermissionClaims)
+			if err != nil {
+				klog.Exit(err, "invalid bind permission claim policy")
+			}
+
+			bindUser, err := contextual.ParseBindUserPolicy(serverCfg.Webhook.BindUser)
+			if err != nil {
+				klog.Exit(err, "invalid bind user policy")
 			}
-			klog.InfoS("using OpenFGA store", "id", storeRes.Stores[0].Id)
 
-			extraAttrClusterKey := serverCfg.Webhook.ClusterKey
+			mappings, err := mapping.Load(serverCfg.Webhook.MappingFile)
+			if err != nil {
+				klog.Exit(err, "invalid mapping file")
+			}
+
+			policyRules, err := policy.Load(serverCfg.PolicyFile)
+			if err != nil {
+				klog.Exit(err, "invalid policy file")
+			}
 			cacheMissTracker := retry.NewExpiringRetryTracker[string](ctx, serverCfg.Webhook.CacheMissMaxRetries, serverCfg.Webhook.CacheMissTTL)
+			policyHandler, err := policy.New(clusterCache, policyRules,
+				policy.WithCacheMissRetry(cacheMissTracker, serverCfg.Webhook.CacheMissRetryAfter),
+			)
+			if err != nil {
+				klog.Exit(err, "invalid policy rules")
+			}
+
+			bypassAction, err := bypass.ParseAction(serverCfg.Bypass.Action)
+			if err != nil {
+				klog.Exit(err, "invalid bypass action")
+			}
+			bypassHandler, err := bypass.New(serverCfg.Bypass.Users, serverCfg.Bypass.Groups, bypassAction, serverCfg.Bypass.ServiceAccountClusters)
+			if err != nil {
+				klog.Exit(err, "invalid bypass configuration")
+			}
+
+			var preFGAHandlers []authorization.Handler
+			if serverCfg.Webhook.EnforceScopes {
+				preFGAHandlers = append(preFGAHandlers, scopes.New())
+			}
+			preFGAHandlers = append(preFGAHandlers, policyHandler, bypassHandler)
+
+			var breakGlassGrants *breakglass.Watcher
+			if serverCfg.BreakGlass.Name != "" {
+				breakGlassGrants = breakglass.New(mgr, serverCfg.BreakGlass.ClusterName, serverCfg.BreakGlass.Namespace, serverCfg.BreakGlass.Name, serverCfg.BreakGlass.ResyncInterval)
+				preFGAHandlers = append(preFGAHandlers, breakglasshandler.New(breakGlassGrants, clusterCache))
+			}
+
+			orgsStore := orgsstore.New(mgr, serverCfg.OrgsStore.ClusterName, serverCfg.OrgsStore.Name, serverCfg.OrgsStore.ResyncInterval)
+
+			var ownerKinds []schema.GroupKind
+			for _, kind := range serverCfg.Webhook.OwnerReferenceKinds {
+				ownerKinds = append(ownerKinds, schema.ParseGroupKind(kind))
+			}
+
 			mgr.GetWebhookServer().Register("/authz", authorization.New(
 				klog.NewKlogr(),
-				union.New(
+				union.New(append(preFGAHandlers,
 					nonresourceattributes.New(serverCfg.Webhook.AllowedNonResourcePrefixes...),
-					orgs.New(fga, mgr, extraAttrClusterKey, storeRes.Stores[0].Id),
-					contextual.New(fga, clusterCache, extraAttrClusterKey, cacheMissTracker, serverCfg.Webhook.CacheMissRetryAfter),
-				),
+					orgs.New(fga, mgr, orgsStore,
+						orgs.WithAuthorizationModels(models),
+						orgs.WithRelationValidation(serverCfg.OpenFGAValidateRelations),
+						orgs.WithConsistency(consistency),
+						orgs.WithIdentityMapper(identities),
+						orgs.WithDenyRelation(serverCfg.OpenFGADenyRelationFormat),
+						orgs.WithCheckContext(checkContext),
+						orgs.WithTypeNaming(typeNaming),
+						orgs.WithOrgsCluster(serverCfg.OrgsStore.ClusterName),
+					),
+					impersonation.New(fga, clusterCache,
+						impersonation.WithIdentityMapper(identities),
+						impersonation.WithAuthorizationModels(models),
+						impersonation.WithRelationValidation(serverCfg.OpenFGAValidateRelations),
+						impersonation.WithConsistency(consistency),
+						impersonation.WithDenyRelation(serverCfg.OpenFGADenyRelationFormat),
+						impersonation.WithCheckContext(checkContext),
+						impersonation.WithGroups(groups),
+						impersonation.WithCacheMissRetry(cacheMissTracker, serverCfg.Webhook.CacheMissRetryAfter),
+					),
+					contextual.New(fga, clusterCache, cacheMissTracker, serverCfg.Webhook.CacheMissRetryAfter,
+						contextual.WithSubresourcesInheritingVerb(serverCfg.Webhook.SubresourcesInheritingVerb...),
+						contextual.WithVerbAliases(serverCfg.Webhook.VerbAliases),
+						contextual.WithUnknownVerbPolicy(unknownVerbPolicy),
+						contextual.WithOwnerReferences(serverCfg.Webhook.OwnerReferencesDepth, ownerKinds...),
+						contextual.WithTypeNaming(typeNaming),
+						contextual.WithMappings(mappings),
+						contextual.WithAuthorizationModels(models),
+						contextual.WithRelationValidation(serverCfg.OpenFGAValidateRelations),
+						contextual.WithConsistency(consistency),
+						contextual.WithIdentityMapper(identities),
+						contextual.WithDenyRelation(serverCfg.OpenFGADenyRelationFormat),
+						contextual.WithCheckContext(checkContext),
+						contextual.WithGroups(groups),
+						contextual.WithPermissionClaims(permissionClaims, mgr),
+						contextual.WithBindUser(bindUser),
+					),
+				)...),
+				authorization.WithClusterResolver(clusters),
 			))
 
 			if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -126,6 +266,14 @@ func NewServeCmd() *cobra.Command {
 			if err := mgr.Add(clusterCache); err != nil {
 				klog.Exit(err, "unable to register cluster cache")
 			}
+			if err := mgr.Add(orgsStore); err != nil {
+				klog.Exit(err, "unable to register orgs store watcher")
+			}
+			if breakGlassGrants != nil {
+				if err := mgr.Add(breakGlassGrants); err != nil {
+					klog.Exit(err, "unable to register break-glass grants watcher")
+				}
+			}
 
 			klog.Info("starting manager")
 			if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
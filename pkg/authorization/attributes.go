@@ -0,0 +1,146 @@
+package authorization
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+// ClusterResolver determines the logical cluster of a request from its Extra
+// attributes.
+type ClusterResolver interface {
+	ClusterName(extra map[string]authorizationv1.ExtraValue) (string, bool)
+	// Path returns the workspace path of a request, if any.
+	Path(extra map[string]authorizationv1.ExtraValue) (string, bool)
+}
+
+// Attributes is a normalized view of a SubjectAccessReview, parsed once per
+// request and shared by all handlers.
+type Attributes struct {
+	// ClusterName is the logical cluster of the request, empty if unknown.
+	ClusterName string
+	// ClusterPath is the workspace path of the request if it could not be
+	// resolved to ClusterName, e.g. because its cluster is not engaged yet.
+	ClusterPath string
+
+	User   string
+	UID    string
+	Groups []string
+	// Scopes are the kcp scopes restricting the user, each a comma separated
+	// list of scopes like cluster:<name>.
+	Scopes []string
+
+	Verb string
+	// ResourceRequest is true for requests on resources, false for requests
+	// on non-resource paths.
+	ResourceRequest bool
+	Resource        schema.GroupVersionResource
+	Subresource     string
+	Namespace       string
+	Name            string
+	// LabelSelector and FieldSelector restrict list and watch requests. They
+	// are nil if the request has no selector or it cannot be parsed.
+	LabelSelector labels.Selector
+	FieldSelector fields.Selector
+
+	// Path is the path of non-resource requests.
+	Path string
+}
+
+// NewAttributes parses the attributes of sar. The cluster name is resolved
+// with clusters, which may be nil.
+func NewAttributes(sar authorizationv1.SubjectAccessReview, clusters ClusterResolver) (Attributes, error) {
+	spec := sar.Spec
+	attrs := Attributes{
+		User:   spec.User,
+		UID:    spec.UID,
+		Groups: spec.Groups,
+		Scopes: spec.Extra[identity.ScopesKey],
+	}
+	if clusters != nil {
+		var found bool
+		if attrs.ClusterName, found = clusters.ClusterName(spec.Extra); !found {
+			attrs.ClusterPath, _ = clusters.Path(spec.Extra)
+		}
+	}
+
+	switch {
+	case spec.ResourceAttributes != nil && spec.NonResourceAttributes != nil:
+		return Attributes{}, errors.New("request contains both resource and non-resource attributes")
+	case spec.ResourceAttributes != nil:
+		ra := spec.ResourceAttributes
+		attrs.ResourceRequest = true
+		attrs.Verb = ra.Verb
+		attrs.Resource = schema.GroupVersionResource{Group: ra.Group, Version: ra.Version, Resource: ra.Resource}
+		attrs.Subresource = ra.Subresource
+		attrs.Namespace = ra.Namespace
+		attrs.Name = ra.Name
+
+		// no handler requires the selectors, so selectors which cannot be
+		// parsed are left out instead of failing the request
+		var err error
+		if attrs.LabelSelector, err = labelSelector(ra.LabelSelector); err != nil {
+			klog.V(2).ErrorS(err, "failed to parse label selector, ignoring it", "verb", ra.Verb, "resource", ra.Resource)
+			attrs.LabelSelector = nil
+		}
+		if attrs.FieldSelector, err = fieldSelector(ra.FieldSelector); err != nil {
+			klog.V(2).ErrorS(err, "failed to parse field selector, ignoring it", "verb", ra.Verb, "resource", ra.Resource)
+			attrs.FieldSelector = nil
+		}
+	case spec.NonResourceAttributes != nil:
+		attrs.Verb = spec.NonResourceAttributes.Verb
+		attrs.Path = spec.NonResourceAttributes.Path
+	}
+
+	return attrs, nil
+}
+
+func labelSelector(attrs *authorizationv1.LabelSelectorAttributes) (labels.Selector, error) {
+	switch {
+	case attrs == nil:
+		return nil, nil
+	case attrs.RawSelector != "":
+		return labels.Parse(attrs.RawSelector)
+	case len(attrs.Requirements) > 0:
+		return metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchExpressions: attrs.Requirements})
+	default:
+		return nil, nil
+	}
+}
+
+func fieldSelector(attrs *authorizationv1.FieldSelectorAttributes) (fields.Selector, error) {
+	switch {
+	case attrs == nil:
+		return nil, nil
+	case attrs.RawSelector != "":
+		return fields.ParseSelector(attrs.RawSelector)
+	case len(attrs.Requirements) > 0:
+		// fields.Selector only supports (in)equality, which is all the
+		// apiserver derives from field selectors anyway.
+		selectors := make([]fields.Selector, 0, len(attrs.Requirements))
+		for _, req := range attrs.Requirements {
+			if len(req.Values) != 1 {
+				return nil, fmt.Errorf("requirement on %q with %d values is not supported", req.Key, len(req.Values))
+			}
+			switch req.Operator {
+			case metav1.FieldSelectorOpIn:
+				selectors = append(selectors, fields.OneTermEqualSelector(req.Key, req.Values[0]))
+			case metav1.FieldSelectorOpNotIn:
+				selectors = append(selectors, fields.OneTermNotEqualSelector(req.Key, req.Values[0]))
+			default:
+				return nil, fmt.Errorf("operator %q on %q is not supported", req.Operator, req.Key)
+			}
+		}
+		return fields.AndSelectors(selectors...), nil
+	default:
+		return nil, nil
+	}
+}
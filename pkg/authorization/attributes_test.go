@@ -0,0 +1,126 @@
+package authorization_test
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const clusterKey = "authorization.kubernetes.io/cluster-name"
+
+func TestNewAttributes(t *testing.T) {
+	clusters := clustercache.NewResolver(nil, clusterKey)
+
+	t.Run("should parse resource requests", func(t *testing.T) {
+		attrs, err := authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			User:   "alice",
+			UID:    "1234",
+			Groups: []string{"developers"},
+			Extra: map[string]v1.ExtraValue{
+				clusterKey:         {"tenant-cluster"},
+				identity.ScopesKey: {"cluster:tenant-cluster"},
+			},
+			ResourceAttributes: &v1.ResourceAttributes{
+				Verb:          "list",
+				Group:         "apps",
+				Version:       "v1",
+				Resource:      "deployments",
+				Subresource:   "status",
+				Namespace:     "default",
+				LabelSelector: &v1.LabelSelectorAttributes{RawSelector: "app=web"},
+				FieldSelector: &v1.FieldSelectorAttributes{RawSelector: "metadata.name=web"},
+			},
+		}}, clusters)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "tenant-cluster", attrs.ClusterName)
+		assert.Equal(t, "alice", attrs.User)
+		assert.Equal(t, "1234", attrs.UID)
+		assert.Equal(t, []string{"developers"}, attrs.Groups)
+		assert.Equal(t, []string{"cluster:tenant-cluster"}, attrs.Scopes)
+		assert.True(t, attrs.ResourceRequest)
+		assert.Equal(t, "list", attrs.Verb)
+		assert.Equal(t, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, attrs.Resource)
+		assert.Equal(t, "status", attrs.Subresource)
+		assert.Equal(t, "default", attrs.Namespace)
+		assert.True(t, attrs.LabelSelector.Matches(labels.Set{"app": "web"}))
+		assert.True(t, attrs.FieldSelector.Matches(fields.Set{"metadata.name": "web"}))
+	})
+
+	t.Run("should parse selector requirements", func(t *testing.T) {
+		attrs, err := authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			ResourceAttributes: &v1.ResourceAttributes{
+				Verb:     "list",
+				Resource: "pods",
+				LabelSelector: &v1.LabelSelectorAttributes{Requirements: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"web", "api"}},
+				}},
+				FieldSelector: &v1.FieldSelectorAttributes{Requirements: []metav1.FieldSelectorRequirement{
+					{Key: "spec.nodeName", Operator: metav1.FieldSelectorOpIn, Values: []string{"node-1"}},
+				}},
+			},
+		}}, clusters)
+		assert.NoError(t, err)
+
+		assert.True(t, attrs.LabelSelector.Matches(labels.Set{"app": "api"}))
+		assert.False(t, attrs.LabelSelector.Matches(labels.Set{"app": "db"}))
+		assert.True(t, attrs.FieldSelector.Matches(fields.Set{"spec.nodeName": "node-1"}))
+	})
+
+	t.Run("should parse non-resource requests", func(t *testing.T) {
+		attrs, err := authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			NonResourceAttributes: &v1.NonResourceAttributes{Verb: "get", Path: "/healthz"},
+		}}, nil)
+		assert.NoError(t, err)
+
+		assert.False(t, attrs.ResourceRequest)
+		assert.Equal(t, "get", attrs.Verb)
+		assert.Equal(t, "/healthz", attrs.Path)
+		assert.Empty(t, attrs.ClusterName)
+		assert.Nil(t, attrs.LabelSelector)
+	})
+
+	t.Run("should keep workspace paths which cannot be resolved", func(t *testing.T) {
+		attrs, err := authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			Extra: map[string]v1.ExtraValue{clusterKey: {"root:orgs:acme"}},
+		}}, clusters)
+		assert.NoError(t, err)
+
+		assert.Empty(t, attrs.ClusterName)
+		assert.Equal(t, "root:orgs:acme", attrs.ClusterPath)
+	})
+
+	t.Run("should ignore selectors which cannot be parsed", func(t *testing.T) {
+		attrs, err := authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			ResourceAttributes: &v1.ResourceAttributes{
+				Verb:          "list",
+				Resource:      "pods",
+				LabelSelector: &v1.LabelSelectorAttributes{RawSelector: "app in (web"},
+			},
+		}}, clusters)
+		assert.NoError(t, err)
+		assert.Equal(t, "list", attrs.Verb)
+		assert.Nil(t, attrs.LabelSelector)
+
+		attrs, err = authorization.NewAttributes(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+			ResourceAttributes: &v1.ResourceAttributes{
+				Verb:     "list",
+				Resource: "pods",
+				FieldSelector: &v1.FieldSelectorAttributes{Requirements: []metav1.FieldSelectorRequirement{
+					{Key: "spec.nodeName", Operator: metav1.FieldSelectorOpExists},
+				}},
+			},
+		}}, clusters)
+		assert.NoError(t, err)
+		assert.Nil(t, attrs.FieldSelector)
+	})
+}
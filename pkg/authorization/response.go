@@ -65,6 +65,14 @@ func Denied() Response {
 	}
 }
 
+// DeniedWithReason returns a denied response explaining why the request
+// was denied.
+func DeniedWithReason(reason string) Response {
+	res := Denied()
+	res.Status.Reason = reason
+	return res
+}
+
 // Retry makes the apiserver retry the request after a given duration
 func Retry(after time.Duration) Response {
 	// note: while setting a SubjectAccessReview won't have any effect because
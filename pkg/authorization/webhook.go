@@ -53,19 +53,48 @@ type Webhook struct {
 	// Handler actually processes an authorization request returning whether it was authorized or unauthorized.
 	Handler Handler
 
-	log logr.Logger
+	log      logr.Logger
+	clusters ClusterResolver
 }
 
-func New(log logr.Logger, handler Handler) *Webhook {
-	return &Webhook{
+// Option configures optional behavior of the webhook.
+type Option func(*Webhook)
+
+// WithClusterResolver resolves the logical cluster of requests into
+// Attributes.ClusterName.
+func WithClusterResolver(clusters ClusterResolver) Option {
+	return func(wh *Webhook) {
+		wh.clusters = clusters
+	}
+}
+
+func New(log logr.Logger, handler Handler, opts ...Option) *Webhook {
+	wh := &Webhook{
 		Handler: handler,
 		log:     log.WithName("webhook"),
 	}
+	for _, opt := range opts {
+		opt(wh)
+	}
+	return wh
 }
 
 // Request defines the input for an authorization handler.
 type Request struct {
 	authorizationv1.SubjectAccessReview
+
+	// Attributes are the parsed attributes of the SubjectAccessReview.
+	Attributes Attributes
+}
+
+// NewRequest returns the request of sar with its attributes parsed. The
+// cluster name is resolved with clusters, which may be nil.
+func NewRequest(sar authorizationv1.SubjectAccessReview, clusters ClusterResolver) (Request, error) {
+	attrs, err := NewAttributes(sar, clusters)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{SubjectAccessReview: sar, Attributes: attrs}, nil
 }
 
 // Response is the output of an authorization handler.
@@ -111,9 +140,9 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// be decoded into the v1 type. However the runtime codec's decoder guesses which type to
 	// decode into by type name if an Object's TypeMeta isn't set. By setting TypeMeta of an
 	// unregistered type to the v1 GVK, the decoder will coerce a v1beta1 SubjectAccessReview to authenticationv1.
-	req := Request{}
+	review := authorizationv1.SubjectAccessReview{}
 	sar := unversionedSubjectAccessReview{}
-	sar.SubjectAccessReview = &req.SubjectAccessReview
+	sar.SubjectAccessReview = &review
 	sar.SetGroupVersionKind(authorizationv1.SchemeGroupVersion.WithKind("SubjectAccessReview"))
 
 	_, _, err = authorizationCodecs.UniversalDecoder().Decode(body, nil, &sar)
@@ -123,6 +152,15 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, err := NewRequest(review, wh.clusters)
+	if err != nil {
+		wh.log.Error(err, "invalid request attributes")
+		res := Errored(err)
+		res.UID = review.UID
+		wh.writeResponse(w, res)
+		return
+	}
+
 	// TODO: think of log constructor
 	wh.log.V(5).Info("received request")
 
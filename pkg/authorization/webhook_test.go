@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
 	"github.com/stretchr/testify/assert"
 
 	v1 "k8s.io/api/authorization/v1"
@@ -22,6 +23,7 @@ func TestServeHTTP(t *testing.T) {
 	testCases := []struct {
 		name               string
 		handler            authorization.Handler
+		opts               []authorization.Option
 		req                func() *http.Request
 		responseAssertions func(*testing.T, *http.Response)
 	}{
@@ -144,10 +146,79 @@ func TestServeHTTP(t *testing.T) {
 				assert.Error(t, err, "response body should not contain a SubjectAccessReview when Retry is returned")
 			},
 		},
+		{
+			name: "should pass the parsed attributes to the handler",
+			req: func() *http.Request {
+				var buffer bytes.Buffer
+				sar := v1.SubjectAccessReview{
+					ObjectMeta: metav1.ObjectMeta{UID: "1234"},
+					Spec: v1.SubjectAccessReviewSpec{
+						User:  "alice",
+						Extra: map[string]v1.ExtraValue{"authorization.kubernetes.io/cluster-name": {"tenant-cluster"}},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Verb:     "get",
+							Group:    "apps",
+							Version:  "v1",
+							Resource: "deployments",
+						},
+					},
+				}
+				err := json.NewEncoder(&buffer).Encode(sar)
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest(http.MethodPost, "/authorize", &buffer)
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			opts: []authorization.Option{
+				authorization.WithClusterResolver(clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name")),
+			},
+			handler: authorization.HandlerFunc(func(ctx context.Context, r authorization.Request) authorization.Response {
+				if r.Attributes.ClusterName != "tenant-cluster" || r.Attributes.Resource.Resource != "deployments" {
+					return authorization.NoOpinion()
+				}
+				return authorization.Allowed()
+			}),
+			responseAssertions: func(t *testing.T, res *http.Response) {
+				var sar v1.SubjectAccessReview
+				err := json.NewDecoder(res.Body).Decode(&sar)
+				assert.NoError(t, err)
+
+				assert.True(t, sar.Status.Allowed)
+			},
+		},
+		{
+			name: "should fail for invalid attributes",
+			req: func() *http.Request {
+				var buffer bytes.Buffer
+				sar := v1.SubjectAccessReview{
+					ObjectMeta: metav1.ObjectMeta{UID: "1234"},
+					Spec: v1.SubjectAccessReviewSpec{
+						ResourceAttributes:    &v1.ResourceAttributes{Verb: "get", Resource: "pods"},
+						NonResourceAttributes: &v1.NonResourceAttributes{Verb: "get", Path: "/healthz"},
+					},
+				}
+				err := json.NewEncoder(&buffer).Encode(sar)
+				assert.NoError(t, err)
+
+				req := httptest.NewRequest(http.MethodPost, "/authorize", &buffer)
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			responseAssertions: func(t *testing.T, res *http.Response) {
+				var sar v1.SubjectAccessReview
+				err := json.NewDecoder(res.Body).Decode(&sar)
+				assert.NoError(t, err)
+
+				assert.False(t, sar.Status.Allowed)
+				assert.Equal(t, types.UID("1234"), sar.UID)
+				assert.Equal(t, "request contains both resource and non-resource attributes", sar.Status.EvaluationError)
+			},
+		},
 	}
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
-			wh := authorization.New(klog.NewKlogr(), test.handler)
+			wh := authorization.New(klog.NewKlogr(), test.handler, test.opts...)
 
 			res := httptest.NewRecorder()
 
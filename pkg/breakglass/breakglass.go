@@ -0,0 +1,252 @@
+package breakglass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/yaml"
+
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+// Any matches every verb in Grant.Verbs.
+const Any = "*"
+
+var configMapGVK = schema.GroupVersionKind{
+	Version: "v1",
+	Kind:    "ConfigMap",
+}
+
+// Grant temporarily allows users or groups the verbs in the clusters or
+// organizations listed. Grants are stored as YAML values of a ConfigMap
+// keyed by their name.
+type Grant struct {
+	Name string `json:"-"`
+
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	// Clusters are logical cluster names, Orgs organization names.
+	Clusters []string `json:"clusters,omitempty"`
+	Orgs     []string `json:"orgs,omitempty"`
+	Verbs    []string `json:"verbs"`
+	// NonResourceURLs are the paths of non-resource requests covered by the
+	// grant, like in RBAC rules a trailing * matches any suffix. Grants
+	// without them only cover resource requests.
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty"`
+
+	Expires       time.Time `json:"expires"`
+	Justification string    `json:"justification"`
+}
+
+// Validate reports grants that would match nothing or are not accountable.
+func (g Grant) Validate() error {
+	switch {
+	case len(g.Users) == 0 && len(g.Groups) == 0:
+		return errors.New("grant without users or groups")
+	case len(g.Clusters) == 0 && len(g.Orgs) == 0:
+		return errors.New("grant without clusters or orgs")
+	case len(g.Verbs) == 0:
+		return errors.New("grant without verbs")
+	case g.Expires.IsZero():
+		return errors.New("grant without expiry")
+	case g.Justification == "":
+		return errors.New("grant without justification")
+	}
+	return nil
+}
+
+// Matches reports whether the grant covers a request, regardless of its expiry.
+// nonResourcePath is the path of non-resource requests and empty for resource
+// requests.
+func (g Grant) Matches(user string, groups []string, clusterName, orgName, verb, nonResourcePath string) bool {
+	subject := slices.Contains(g.Users, user) || slices.ContainsFunc(groups, func(group string) bool {
+		return slices.Contains(g.Groups, group)
+	})
+	scope := slices.Contains(g.Clusters, clusterName) || (orgName != "" && slices.Contains(g.Orgs, orgName))
+	verbs := slices.Contains(g.Verbs, Any) || slices.Contains(g.Verbs, verb)
+	paths := nonResourcePath == "" || slices.ContainsFunc(g.NonResourceURLs, func(url string) bool {
+		prefix, wildcard := strings.CutSuffix(url, Any)
+		return url == nonResourcePath || (wildcard && strings.HasPrefix(nonResourcePath, prefix))
+	})
+	return subject && scope && verbs && paths
+}
+
+// Expired reports whether the grant has expired at now.
+func (g Grant) Expired(now time.Time) bool {
+	return !now.Before(g.Expires)
+}
+
+// Equal reports whether g and other grant the same access.
+func (g Grant) Equal(other Grant) bool {
+	return g.Name == other.Name &&
+		slices.Equal(g.Users, other.Users) &&
+		slices.Equal(g.Groups, other.Groups) &&
+		slices.Equal(g.Clusters, other.Clusters) &&
+		slices.Equal(g.Orgs, other.Orgs) &&
+		slices.Equal(g.Verbs, other.Verbs) &&
+		slices.Equal(g.NonResourceURLs, other.NonResourceURLs) &&
+		g.Expires.Equal(other.Expires) &&
+		g.Justification == other.Justification
+}
+
+// Provider yields the current break-glass grants.
+type Provider interface {
+	Grants() []Grant
+}
+
+// Watcher reads grants from a ConfigMap through an informer and keeps them up
+// to date.
+type Watcher struct {
+	lock   sync.RWMutex
+	grants []Grant
+
+	mgr         mcmanager.Manager
+	clusterName multicluster.ClusterName
+	key         types.NamespacedName
+	interval    time.Duration
+}
+
+var _ Provider = &Watcher{}
+var _ mcmanager.Runnable = &Watcher{}
+
+// New returns a Watcher watching the ConfigMap namespace/name in clusterName,
+// re-parsing its grants every interval.
+func New(mgr mcmanager.Manager, clusterName, namespace, name string, interval time.Duration) *Watcher {
+	return &Watcher{
+		mgr:         mgr,
+		clusterName: multicluster.ClusterName(clusterName),
+		key:         types.NamespacedName{Namespace: namespace, Name: name},
+		interval:    interval,
+	}
+}
+
+// Grants implements Provider.
+func (w *Watcher) Grants() []Grant {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.grants
+}
+
+// Start watches the ConfigMap through an informer of the cluster until ctx
+// is cancelled. Grants are re-parsed every interval.
+func (w *Watcher) Start(ctx context.Context) error {
+	var cl cluster.Cluster
+	err := wait.PollUntilContextCancel(ctx, w.interval, true, func(ctx context.Context) (bool, error) {
+		var err error
+		cl, err = w.mgr.GetCluster(ctx, w.clusterName)
+		if err != nil {
+			klog.V(5).ErrorS(err, "Break-glass cluster not engaged yet, will retry", "clusterName", w.clusterName)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		// ctx is cancelled
+		return nil
+	}
+
+	cm := &unstructured.Unstructured{}
+	cm.SetGroupVersionKind(configMapGVK)
+	informer, err := cl.GetCache().GetInformer(ctx, cm)
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap informer of cluster %s: %w", w.clusterName, err)
+	}
+
+	registration, err := informer.AddEventHandlerWithResyncPeriod(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    w.update,
+		UpdateFunc: func(_, obj any) { w.update(obj) },
+		DeleteFunc: w.delete,
+	}, w.interval)
+	if err != nil {
+		return fmt.Errorf("failed to watch break-glass ConfigMap: %w", err)
+	}
+
+	<-ctx.Done()
+	return informer.RemoveEventHandler(registration)
+}
+
+// Engage implements multicluster.Aware. The watcher looks up its cluster by
+// name when it is started, so there is nothing to do here.
+func (w *Watcher) Engage(_ context.Context, _ multicluster.ClusterName, _ cluster.Cluster) error { // coverage-ignore
+	return nil
+}
+
+// update replaces the grants with those of obj, if it is the watched
+// ConfigMap.
+func (w *Watcher) update(obj any) {
+	cm, ok := obj.(*unstructured.Unstructured)
+	if !ok || !w.watches(cm) {
+		return
+	}
+
+	data, _, err := unstructured.NestedStringMap(cm.Object, "data")
+	if err != nil {
+		klog.ErrorS(err, "Failed to read break-glass grants, keeping previous grants", "clusterName", w.clusterName, "configMap", w.key)
+		return
+	}
+	w.set(ParseGrants(data))
+}
+
+// delete removes all grants if obj is the watched ConfigMap.
+func (w *Watcher) delete(obj any) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if cm, ok := obj.(*unstructured.Unstructured); ok && w.watches(cm) {
+		w.set(nil)
+	}
+}
+
+func (w *Watcher) watches(cm *unstructured.Unstructured) bool {
+	return cm.GetNamespace() == w.key.Namespace && cm.GetName() == w.key.Name
+}
+
+func (w *Watcher) set(grants []Grant) {
+	w.lock.Lock()
+	previous := w.grants
+	w.grants = grants
+	w.lock.Unlock()
+
+	if !slices.EqualFunc(previous, grants, Grant.Equal) {
+		names := make([]string, 0, len(grants))
+		for _, grant := range grants {
+			names = append(names, grant.Name)
+		}
+		klog.InfoS("Break-glass grants changed", "grants", names)
+	}
+}
+
+// ParseGrants parses the grants of ConfigMap data sorted by name. Invalid
+// grants are logged and skipped.
+func ParseGrants(data map[string]string) []Grant {
+	var grants []Grant
+	for name, value := range data {
+		grant := Grant{}
+		if err := yaml.UnmarshalStrict([]byte(value), &grant); err != nil {
+			klog.ErrorS(fmt.Errorf("failed to parse grant: %w", err), "Skipping break-glass grant", "grant", name)
+			continue
+		}
+		if err := grant.Validate(); err != nil {
+			klog.ErrorS(err, "Skipping break-glass grant", "grant", name)
+			continue
+		}
+		grant.Name = name
+		grants = append(grants, grant)
+	}
+	sort.Slice(grants, func(i, j int) bool { return grants[i].Name < grants[j].Name })
+	return grants
+}
@@ -0,0 +1,179 @@
+package breakglass_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/breakglass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const sreGrant = `
+users: [sre-alice]
+clusters: [tenant-cluster]
+verbs: [get, list]
+expires: "2030-01-01T00:00:00Z"
+justification: INC-42 tenant outage
+`
+
+func TestParseGrants(t *testing.T) {
+	grants := breakglass.ParseGrants(map[string]string{
+		"incident-42":      sreGrant,
+		"no-justification": "users: [bob]\norgs: [acme]\nverbs: ['*']\nexpires: \"2030-01-01T00:00:00Z\"\n",
+		"unknown-field":    sreGrant + "role: admin\n",
+		"oncall":           "groups: [oncall]\norgs: [acme]\nverbs: ['*']\nexpires: \"2030-01-01T00:00:00Z\"\njustification: INC-43\n",
+	})
+
+	assert.Len(t, grants, 2)
+	assert.Equal(t, "incident-42", grants[0].Name)
+	assert.Equal(t, []string{"sre-alice"}, grants[0].Users)
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), grants[0].Expires.UTC())
+	assert.Equal(t, "oncall", grants[1].Name)
+}
+
+func TestGrant_Matches(t *testing.T) {
+	grant := breakglass.Grant{
+		Users:    []string{"sre-alice"},
+		Groups:   []string{"oncall"},
+		Clusters: []string{"tenant-cluster"},
+		Orgs:     []string{"acme"},
+		Verbs:    []string{"get"},
+	}
+
+	assert.True(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", ""))
+	assert.True(t, grant.Matches("bob", []string{"oncall"}, "other-cluster", "acme", "get", ""))
+	assert.False(t, grant.Matches("bob", []string{"developers"}, "tenant-cluster", "", "get", ""))
+	assert.False(t, grant.Matches("sre-alice", nil, "other-cluster", "", "get", ""))
+	assert.False(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "delete", ""))
+
+	grant.Verbs = []string{breakglass.Any}
+	assert.True(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "delete", ""))
+
+	// non-resource requests are only covered by their paths
+	assert.False(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", "/metrics"))
+	grant.NonResourceURLs = []string{"/metrics", "/debug/*"}
+	assert.True(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", "/metrics"))
+	assert.True(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", "/debug/pprof/heap"))
+	assert.False(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", "/metrics/extra"))
+	assert.False(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "get", "/healthz"))
+	assert.True(t, grant.Matches("sre-alice", nil, "tenant-cluster", "", "delete", ""), "resource requests are still covered")
+}
+
+func TestGrant_Expired(t *testing.T) {
+	grant := breakglass.Grant{Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.False(t, grant.Expired(time.Date(2029, 12, 31, 23, 59, 0, 0, time.UTC)))
+	assert.True(t, grant.Expired(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestGrant_Equal(t *testing.T) {
+	grants := breakglass.ParseGrants(map[string]string{"incident-42": sreGrant})
+	assert.Len(t, grants, 1)
+	grant := grants[0]
+
+	assert.True(t, grant.Equal(breakglass.ParseGrants(map[string]string{"incident-42": sreGrant})[0]))
+
+	widened := breakglass.ParseGrants(map[string]string{"incident-42": strings.Replace(sreGrant, "verbs: [get, list]", "verbs: ['*']", 1)})[0]
+	assert.False(t, grant.Equal(widened), "grants with other verbs differ")
+
+	extended := grant
+	extended.Clusters = []string{"tenant-cluster", "other-cluster"}
+	assert.False(t, grant.Equal(extended), "grants with other clusters differ")
+
+	renamed := grant
+	renamed.Name = "incident-43"
+	assert.False(t, grant.Equal(renamed))
+}
+
+// registeringInformer signals when an event handler is added.
+type registeringInformer struct {
+	*controllertest.FakeInformer
+	registered chan struct{}
+}
+
+func (i registeringInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) (toolscache.ResourceEventHandlerRegistration, error) {
+	registration, err := i.FakeInformer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	close(i.registered)
+	return registration, err
+}
+
+func TestWatcher(t *testing.T) {
+	key := types.NamespacedName{Namespace: "platform-mesh-system", Name: "break-glass"}
+	configMap := func(namespace, name string, data map[string]any) *unstructured.Unstructured {
+		cm := &unstructured.Unstructured{Object: map[string]any{"data": data}}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetNamespace(namespace)
+		cm.SetName(name)
+		return cm
+	}
+
+	mgr := mocks.NewManager(t)
+	cluster := mocks.NewCluster(t)
+	informer := registeringInformer{FakeInformer: &controllertest.FakeInformer{Synced: true}, registered: make(chan struct{})}
+	informers := &informertest.FakeInformers{InformersByGVK: map[schema.GroupVersionKind]toolscache.SharedIndexInformer{
+		{Version: "v1", Kind: "ConfigMap"}: informer,
+	}}
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root")).Return(nil, errors.New("not engaged")).Once()
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root")).Return(cluster, nil).Once()
+	cluster.EXPECT().GetCache().Return(informers)
+
+	w := breakglass.New(mgr, "root", key.Namespace, key.Name, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, w.Start(ctx))
+	}()
+
+	// the handler is added once the cluster is engaged
+	select {
+	case <-informer.registered:
+	case <-time.After(time.Second):
+		t.Fatal("event handler not added")
+	}
+
+	created := configMap(key.Namespace, key.Name, map[string]any{"incident-42": sreGrant})
+	informer.Add(created)
+	assert.Len(t, w.Grants(), 1, "should read grants of the added ConfigMap")
+
+	informer.Add(configMap("default", key.Name, map[string]any{}))
+	assert.Len(t, w.Grants(), 1, "should ignore other ConfigMaps")
+
+	updated := configMap(key.Namespace, key.Name, map[string]any{
+		"incident-42": sreGrant,
+		"incident-43": strings.Replace(sreGrant, "INC-42", "INC-43", 1),
+	})
+	informer.Update(created, updated)
+	assert.Len(t, w.Grants(), 2, "should read grants of the updated ConfigMap")
+
+	informer.Delete(updated)
+	assert.Empty(t, w.Grants(), "should have no grants without ConfigMap")
+
+	cancel()
+	<-done
+}
+
+func TestWatcher_InformerError(t *testing.T) {
+	mgr := mocks.NewManager(t)
+	cluster := mocks.NewCluster(t)
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root")).Return(cluster, nil)
+	cluster.EXPECT().GetCache().Return(&informertest.FakeInformers{Error: errors.New("forbidden")})
+
+	w := breakglass.New(mgr, "root", "platform-mesh-system", "break-glass", time.Second)
+	assert.Error(t, w.Start(t.Context()))
+	assert.Empty(t, w.Grants())
+}
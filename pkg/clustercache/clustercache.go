@@ -3,6 +3,7 @@ package clustercache
 import (
 	"context"
 	"errors"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,6 +25,9 @@ import (
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 
 	"github.com/kcp-dev/logicalcluster/v3"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 )
 
 type ClusterInfo struct {
@@ -30,43 +35,191 @@ type ClusterInfo struct {
 	RESTMapper      meta.RESTMapper
 	AccountName     string
 	ParentClusterID string
+	// OrgName is the organization the cluster belongs to.
+	OrgName string
+	// AuthorizationModelID is the model pinned on the org's Store object, if any.
+	AuthorizationModelID string
+	// Client reads objects of the cluster from its informer cache. Reading
+	// PartialObjectMetadata starts a metadata-only informer for the kind on
+	// first use.
+	Client client.Reader
+	// Path is the workspace path of the cluster, e.g. root:orgs:acme.
+	Path string
+	// Types records the object types of the resources resolved through
+	// RESTMapper to detect collisions. It is nil if collisions are not
+	// detected.
+	Types *util.TypeRegistry
 }
 
+// State is the lifecycle state of a cluster in the cache.
+type State string
+
+const (
+	// StateUnknown is the state of clusters which have not been engaged.
+	StateUnknown State = ""
+	// StateEngaging is the state of clusters whose info is being read.
+	// Clusters in StateReady stay ready while they are engaged again.
+	StateEngaging State = "Engaging"
+	// StateReady is the state of clusters whose info is cached.
+	StateReady State = "Ready"
+	// StateIgnored is the state of clusters outside of organizations, which
+	// are never cached.
+	StateIgnored State = "Ignored"
+	// StateFailed is the state of clusters whose info could not be read.
+	StateFailed State = "Failed"
+)
+
 type Provider interface {
 	mcmanager.Runnable
+	// Get returns the info of a cluster in StateReady.
 	Get(clusterName multicluster.ClusterName) (ClusterInfo, bool)
+	// State returns the lifecycle state of a cluster, and the error engaging
+	// it failed with in StateFailed.
+	State(clusterName multicluster.ClusterName) (State, error)
+	// ClusterName resolves the workspace path of an engaged cluster to its
+	// logical cluster name.
+	ClusterName(path string) (multicluster.ClusterName, bool)
+}
+
+// entry is a cluster in the cache. info is only set in StateReady.
+type entry struct {
+	info  ClusterInfo
+	state State
+	err   error
 }
 
 type clusterCache struct {
 	lock  sync.RWMutex
-	cache map[multicluster.ClusterName]ClusterInfo
+	cache map[multicluster.ClusterName]entry
+	// paths indexes every engaged cluster by workspace path, including
+	// clusters outside of organizations which are not cached.
+	paths map[string]multicluster.ClusterName
 	mgr   mcmanager.Manager
+
+	// typeNaming derives the object types checked for collisions in the
+	// clusters' type registries. Collisions are not detected if empty.
+	typeNaming        util.TypeNaming
+	maxRelationLength int
+
+	// resyncInterval is the interval at which the Store objects of cached
+	// orgs are re-read. Stores are only read on Engage if 0.
+	resyncInterval time.Duration
 }
 
-func New(mgr mcmanager.Manager) (*clusterCache, error) {
+var storeGVK = schema.GroupVersionKind{
+	Group:   "core.platform-mesh.io",
+	Version: "v1alpha1",
+	Kind:    "Store",
+}
 
-	return &clusterCache{
-		cache: make(map[multicluster.ClusterName]ClusterInfo),
+// Option configures optional behavior of the cluster cache.
+type Option func(*clusterCache)
+
+// WithTypeCollisionDetection adds a type registry to the info of every cached
+// cluster, detecting resources whose OpenFGA object types derived with naming
+// collide. The resources served by a cluster are discovered and registered
+// when it is engaged.
+func WithTypeCollisionDetection(naming util.TypeNaming, maxRelationLength int) Option {
+	return func(c *clusterCache) {
+		c.typeNaming = naming
+		c.maxRelationLength = maxRelationLength
+	}
+}
+
+// WithStoreResync re-reads the Store objects of cached orgs every interval.
+func WithStoreResync(interval time.Duration) Option {
+	return func(c *clusterCache) {
+		c.resyncInterval = interval
+	}
+}
+
+func New(mgr mcmanager.Manager, opts ...Option) (*clusterCache, error) {
+	c := &clusterCache{
+		cache: make(map[multicluster.ClusterName]entry),
+		paths: make(map[string]multicluster.ClusterName),
 		mgr:   mgr,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 func NewWithClient(orgsClient client.Client) *clusterCache {
 	return &clusterCache{
-		cache: make(map[multicluster.ClusterName]ClusterInfo),
+		cache: make(map[multicluster.ClusterName]entry),
+		paths: make(map[string]multicluster.ClusterName),
 	}
 }
 
 func (c *clusterCache) Get(clusterName multicluster.ClusterName) (ClusterInfo, bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	val, ok := c.cache[clusterName]
-	return val, ok
+	e, ok := c.cache[clusterName]
+	if !ok || e.state != StateReady {
+		return ClusterInfo{}, false
+	}
+	return e.info, true
+}
+
+func (c *clusterCache) State(clusterName multicluster.ClusterName) (State, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	e := c.cache[clusterName]
+	return e.state, e.err
+}
+
+func (c *clusterCache) setState(name multicluster.ClusterName, state State, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.cache[name].state == StateReady && (state == StateEngaging || state == StateFailed) {
+		// keep serving the previous info of a re-engaged cluster until it
+		// is engaged successfully
+		return
+	}
+	c.cache[name] = entry{state: state, err: err}
+}
+
+// fail records that engaging name failed with err and returns err.
+func (c *clusterCache) fail(name multicluster.ClusterName, err error) error {
+	c.setState(name, StateFailed, err)
+	return err
+}
+
+func (c *clusterCache) ClusterName(path string) (multicluster.ClusterName, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	name, ok := c.paths[path]
+	return name, ok
+}
+
+// setPath indexes name by path, replacing previous paths of name.
+func (c *clusterCache) setPath(name multicluster.ClusterName, path string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for p, n := range c.paths {
+		if n == name {
+			delete(c.paths, p)
+		}
+	}
+	c.paths[path] = name
+}
+
+// removePath removes path from the index once name is disengaged, unless
+// path has been taken over by another cluster.
+func (c *clusterCache) removePath(name multicluster.ClusterName, path string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.paths[path] == name {
+		delete(c.paths, path)
+	}
 }
 
 func (c *clusterCache) Engage(ctx context.Context, name multicluster.ClusterName, cl cluster.Cluster) error {
 	klog.V(5).InfoS("Engaging cluster", "clusterName", name)
 
+	c.setState(name, StateEngaging, nil)
+
 	var lc unstructured.Unstructured
 	err := wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
 		lc = unstructured.Unstructured{}
@@ -83,15 +236,24 @@ func (c *clusterCache) Engage(ctx context.Context, name multicluster.ClusterName
 	})
 	if err != nil {
 		klog.ErrorS(err, "Failed to get LogicalCluster, context cancelled", "clusterName", name)
-		return err
+		return c.fail(name, err)
 	}
 
 	annotationPath := lc.GetAnnotations()["kcp.io/path"]
 	klog.V(5).InfoS("Retrieved logical cluster path", "clusterName", name, "path", annotationPath)
 
+	if annotationPath != "" {
+		c.setPath(name, annotationPath)
+		go func() {
+			<-ctx.Done()
+			c.removePath(name, annotationPath)
+		}()
+	}
+
 	const orgsPrefix = "root:orgs:"
 	if !strings.HasPrefix(annotationPath, orgsPrefix) {
 		klog.V(5).InfoS("Cluster path does not have orgs prefix, skipping", "clusterName", name, "path", annotationPath)
+		c.setState(name, StateIgnored, nil)
 		return nil
 	}
 
@@ -101,59 +263,47 @@ func (c *clusterCache) Engage(ctx context.Context, name multicluster.ClusterName
 	parentClusterID, found, err := unstructured.NestedString(lc.Object, "spec", "owner", "cluster")
 	if err != nil {
 		klog.ErrorS(err, "Failed to get owner.cluster from LogicalCluster spec", "clusterName", name)
-		return err
+		return c.fail(name, err)
 	}
 	if !found {
 		klog.Error("No owner.cluster found in LogicalCluster spec", "clusterName", name)
-		return errors.New("owner.cluster not found in LogicalCluster spec")
+		return c.fail(name, errors.New("owner.cluster not found in LogicalCluster spec"))
 	}
 
-	var store unstructured.Unstructured
+	var store *unstructured.Unstructured
+	var storeID string
 	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
-		store = unstructured.Unstructured{}
-		store.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   "core.platform-mesh.io",
-			Version: "v1alpha1",
-			Kind:    "Store",
-		})
-
-		orgsCluster, err := c.mgr.GetCluster(ctx, "root:orgs")
+		var err error
+		store, err = c.readStore(ctx, orgName)
 		if err != nil {
-			return false, err
-		}
-		orgsClient := orgsCluster.GetClient()
-
-		if err := orgsClient.Get(ctx, types.NamespacedName{Name: orgName}, &store); err != nil {
 			klog.V(5).ErrorS(err, "Failed to get Store for org, will retry", "clusterName", name, "orgName", orgName)
 			return false, nil
 		}
+		// the store is created in OpenFGA after the Store object
+		storeID, _, _ = unstructured.NestedString(store.Object, "status", "storeId")
+		if storeID == "" {
+			klog.V(5).InfoS("storeId not found in Store status, will retry", "clusterName", name, "orgName", orgName)
+			return false, nil
+		}
 		return true, nil
 	})
 	if err != nil {
 		klog.ErrorS(err, "Failed to get Store for org", "clusterName", name, "orgName", orgName)
-		return err
+		return c.fail(name, err)
 	}
 
-	storeID, found, err := unstructured.NestedString(store.Object, "status", "storeId")
-	if err != nil {
-		klog.ErrorS(err, "Failed to get storeId from Store status", "clusterName", name, "orgName", orgName)
-		return err
-	}
-	if !found {
-		klog.V(5).InfoS("storeId not found in Store status", "clusterName", name, "orgName", orgName)
-		return errors.New("storeId not found in Store status")
-	}
+	authorizationModelID := store.GetAnnotations()[openfga.AuthorizationModelIDAnnotation]
 
 	cfg := rest.CopyConfig(cl.GetConfig())
 
 	parsed, err := url.Parse(cfg.Host)
 	if err != nil {
-		return err
+		return c.fail(name, err)
 	}
 
 	path, err := url.JoinPath("clusters", name.String())
 	if err != nil {
-		return err
+		return c.fail(name, err)
 	}
 
 	parsed.Path = path
@@ -161,34 +311,147 @@ func (c *clusterCache) Engage(ctx context.Context, name multicluster.ClusterName
 
 	httpClient, err := rest.HTTPClientFor(cfg)
 	if err != nil {
-		return err
+		return c.fail(name, err)
 	}
 
 	restMapper, err := apiutil.NewDynamicRESTMapper(cfg, httpClient)
 	if err != nil {
-		return err
+		return c.fail(name, err)
+	}
+
+	var types *util.TypeRegistry
+	if c.typeNaming != "" {
+		types = util.NewTypeRegistry(c.typeNaming, c.maxRelationLength)
 	}
 
 	c.lock.Lock()
-	c.cache[name] = ClusterInfo{
-		StoreID:         storeID,
-		RESTMapper:      restMapper,
-		AccountName:     accountName,
-		ParentClusterID: parentClusterID,
+	c.cache[name] = entry{
+		state: StateReady,
+		info: ClusterInfo{
+			StoreID:              storeID,
+			RESTMapper:           restMapper,
+			AccountName:          accountName,
+			ParentClusterID:      parentClusterID,
+			OrgName:              orgName,
+			AuthorizationModelID: authorizationModelID,
+			Client:               cl.GetClient(),
+			Path:                 annotationPath,
+			Types:                types,
+		},
 	}
 	c.lock.Unlock()
 
 	klog.V(5).InfoS("Cached cluster info",
 		"clusterName", name,
 		"storeId", storeID,
+		"authorizationModelID", authorizationModelID,
 		"accountName", accountName,
 		"parentClusterID", parentClusterID)
 
+	if types != nil {
+		registerResources(name, annotationPath, cfg, httpClient, types)
+	}
+
 	return nil
 }
 
-func (c *clusterCache) Start(_ context.Context) error { // coverage-ignore
+// registerResources registers all resources served by the cluster in types,
+// so that collisions are logged on engage rather than only once colliding
+// resources are checked. Resources that cannot be discovered are registered
+// when they are checked.
+func registerResources(name multicluster.ClusterName, path string, cfg *rest.Config, httpClient *http.Client, types *util.TypeRegistry) {
+	dc, err := discovery.NewDiscoveryClientForConfigAndClient(cfg, httpClient)
+	if err != nil {
+		klog.V(2).ErrorS(err, "Failed to create discovery client, skipping type collision detection on engage", "clusterName", name)
+		return
+	}
+
+	lists, err := dc.ServerPreferredResources()
+	if err != nil {
+		// partial results are returned if only some groups fail
+		klog.V(2).ErrorS(err, "Failed to discover resources for type collision detection", "clusterName", name)
+	}
+
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				// subresources share the object type of their resource
+				continue
+			}
+			singular := resource.SingularName
+			if singular == "" {
+				singular = strings.ToLower(resource.Kind)
+			}
+			if collision, ok := types.Register(gv.WithResource(resource.Name), singular); ok {
+				klog.InfoS("Resources map to the same OpenFGA object type",
+					"path", path,
+					"objectType", collision.ObjectType,
+					"resource", collision.Existing,
+					"collidingResource", collision.Colliding)
+			}
+		}
+	}
+}
+
+// readStore reads the Store object of an org from the orgs workspace.
+func (c *clusterCache) readStore(ctx context.Context, orgName string) (*unstructured.Unstructured, error) {
+	orgsCluster, err := c.mgr.GetCluster(ctx, "root:orgs")
+	if err != nil {
+		return nil, err
+	}
+
+	store := &unstructured.Unstructured{}
+	store.SetGroupVersionKind(storeGVK)
+	if err := orgsCluster.GetClient().Get(ctx, types.NamespacedName{Name: orgName}, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Start re-reads the Store objects of all cached orgs every resync interval,
+// so that re-pinned authorization models are picked up.
+func (c *clusterCache) Start(ctx context.Context) error {
+	if c.resyncInterval <= 0 {
+		return nil
+	}
+	wait.UntilWithContext(ctx, c.resyncStores, c.resyncInterval)
 	return nil
 }
 
+func (c *clusterCache) resyncStores(ctx context.Context) {
+	c.lock.RLock()
+	orgs := map[string]bool{}
+	for _, e := range c.cache {
+		if e.state == StateReady {
+			orgs[e.info.OrgName] = true
+		}
+	}
+	c.lock.RUnlock()
+
+	for orgName := range orgs {
+		store, err := c.readStore(ctx, orgName)
+		if err != nil {
+			klog.V(5).ErrorS(err, "Failed to resync Store for org, will retry", "orgName", orgName)
+			continue
+		}
+		authorizationModelID := store.GetAnnotations()[openfga.AuthorizationModelIDAnnotation]
+
+		c.lock.Lock()
+		for name, e := range c.cache {
+			if e.state != StateReady || e.info.OrgName != orgName || e.info.AuthorizationModelID == authorizationModelID {
+				continue
+			}
+			klog.InfoS("Pinned authorization model changed", "clusterName", name, "orgName", orgName,
+				"previousModelID", e.info.AuthorizationModelID, "modelID", authorizationModelID)
+			e.info.AuthorizationModelID = authorizationModelID
+			c.cache[name] = e
+		}
+		c.lock.Unlock()
+	}
+}
+
 var _ Provider = &clusterCache{}
@@ -2,15 +2,23 @@ package clustercache_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,6 +44,7 @@ func TestClusterCache_Engage(t *testing.T) {
 		wantCached      bool
 		wantAccountName string
 		wantErr         bool
+		wantState       clustercache.State
 	}{
 		{
 			name:         "caches cluster info successfully",
@@ -44,34 +53,46 @@ func TestClusterCache_Engage(t *testing.T) {
 			setupOrgsClient: func(c *mocks.Client) {
 				setupStoreGet(c, "myorg", "myorg-store-id")
 			},
-			setupCluster:    func(c *mocks.Cluster) { c.EXPECT().GetConfig().Return(&rest.Config{Host: "https://example.com"}) },
+			setupCluster: func(c *mocks.Cluster) {
+				c.EXPECT().GetConfig().Return(&rest.Config{Host: "https://example.com"})
+			},
 			wantCached:      true,
 			wantAccountName: "child",
+			wantState:       clustercache.StateReady,
 		},
 		{
-			name:    "returns error when owner missing",
-			path:    "root:orgs:myorg",
-			wantErr: true,
+			name:      "returns error when owner missing",
+			path:      "root:orgs:myorg",
+			wantErr:   true,
+			wantState: clustercache.StateFailed,
 		},
 		{
 			name:       "skips non-org path",
 			path:       "root:platform-mesh-system",
 			wantCached: false,
+			wantState:  clustercache.StateIgnored,
 		},
 		{
-			name:     "returns error on logical cluster get failure",
-			path:     "root:orgs:myorg",
-			lcGetErr: errors.New("connection refused"),
-			wantErr:  true,
+			name:      "returns error on logical cluster get failure",
+			path:      "root:orgs:myorg",
+			lcGetErr:  errors.New("connection refused"),
+			wantErr:   true,
+			wantState: clustercache.StateFailed,
 		},
 		{
-			name:         "returns error when storeId missing",
+			name:         "waits for the storeId",
 			path:         "root:orgs:myorg",
 			ownerCluster: "parent-cluster",
 			setupOrgsClient: func(c *mocks.Client) {
 				setupStoreGetMissing(c, "myorg")
+				setupStoreGet(c, "myorg", "myorg-store-id")
+			},
+			setupCluster: func(c *mocks.Cluster) {
+				c.EXPECT().GetConfig().Return(&rest.Config{Host: "https://example.com"})
 			},
-			wantErr: true,
+			wantCached:      true,
+			wantAccountName: "myorg",
+			wantState:       clustercache.StateReady,
 		},
 	}
 
@@ -117,24 +138,254 @@ func TestClusterCache_Engage(t *testing.T) {
 			assert.NoError(t, err)
 			err = cc.Engage(ctx, multicluster.ClusterName("test-cluster"), cl)
 
+			state, stateErr := cc.State(multicluster.ClusterName("test-cluster"))
+			assert.Equal(t, tt.wantState, state)
+
 			if tt.wantErr {
 				assert.Error(t, err)
+				assert.Equal(t, err, stateErr)
+				_, found := cc.Get(multicluster.ClusterName("test-cluster"))
+				assert.False(t, found)
 				return
 			}
 
 			assert.NoError(t, err)
+			assert.NoError(t, stateErr)
+
+			name, found := cc.ClusterName(tt.path)
+			assert.True(t, found)
+			assert.Equal(t, multicluster.ClusterName("test-cluster"), name)
 
 			info, found := cc.Get(multicluster.ClusterName("test-cluster"))
 			assert.Equal(t, tt.wantCached, found)
 			if tt.wantCached {
 				assert.Equal(t, tt.wantAccountName, info.AccountName)
 				assert.Equal(t, tt.ownerCluster, info.ParentClusterID)
+				assert.Equal(t, "myorg", info.OrgName)
 				assert.NotNil(t, info.RESTMapper)
+				// objects are read through the cluster's informer cache,
+				// the mocked cluster fails on live reads via GetAPIReader
+				assert.Same(t, k8sClient, info.Client)
+				assert.Equal(t, "myorg-store-id-model", info.AuthorizationModelID)
+				assert.Equal(t, tt.path, info.Path)
 			}
 		})
 	}
 }
 
+func TestClusterCache_TypeCollisionDetection(t *testing.T) {
+	monitors := func(group string) metav1.APIResourceList {
+		return metav1.APIResourceList{
+			GroupVersion: group + "/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "servicemonitorconfigurations", SingularName: "servicemonitorconfiguration", Kind: "ServiceMonitorConfiguration", Namespaced: true},
+				{Name: "servicemonitorconfigurations/status", Kind: "ServiceMonitorConfiguration", Namespaced: true},
+			},
+		}
+	}
+	discovery := map[string]any{
+		"/clusters/test-cluster/api": metav1.APIVersions{},
+		"/clusters/test-cluster/apis": metav1.APIGroupList{Groups: []metav1.APIGroup{
+			{
+				Name:             "alpha.monitoring.platform-mesh.io",
+				Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "alpha.monitoring.platform-mesh.io/v1", Version: "v1"}},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "alpha.monitoring.platform-mesh.io/v1", Version: "v1"},
+			},
+			{
+				Name:             "beta.monitoring.platform-mesh.io",
+				Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "beta.monitoring.platform-mesh.io/v1", Version: "v1"}},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "beta.monitoring.platform-mesh.io/v1", Version: "v1"},
+			},
+		}},
+		"/clusters/test-cluster/apis/alpha.monitoring.platform-mesh.io/v1": monitors("alpha.monitoring.platform-mesh.io"),
+		"/clusters/test-cluster/apis/beta.monitoring.platform-mesh.io/v1":  monitors("beta.monitoring.platform-mesh.io"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := discovery[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(body))
+	}))
+	defer server.Close()
+
+	cl := mocks.NewCluster(t)
+	k8sClient := mocks.NewClient(t)
+	mgr := mocks.NewManager(t)
+	orgsCluster := mocks.NewCluster(t)
+	orgsClient := mocks.NewClient(t)
+
+	cl.EXPECT().GetClient().Return(k8sClient)
+	cl.EXPECT().GetConfig().Return(&rest.Config{Host: server.URL})
+	k8sClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			lc := obj.(*unstructured.Unstructured)
+			lc.SetAnnotations(map[string]string{"kcp.io/path": "root:orgs:myorg"})
+			lc.Object["spec"] = map[string]any{"owner": map[string]any{"cluster": "parent-cluster"}}
+		}).
+		Return(nil)
+
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(orgsCluster, nil)
+	orgsCluster.EXPECT().GetClient().Return(orgsClient)
+	setupStoreGet(orgsClient, "myorg", "myorg-store-id")
+
+	cc, err := clustercache.New(mgr, clustercache.WithTypeCollisionDetection(util.TypeNamingTruncate, util.MaxRelationLength))
+	assert.NoError(t, err)
+	assert.NoError(t, cc.Engage(t.Context(), multicluster.ClusterName("test-cluster"), cl))
+
+	info, found := cc.Get(multicluster.ClusterName("test-cluster"))
+	assert.True(t, found)
+	assert.NotNil(t, info.Types)
+
+	// both groups were registered on engage, so their collision was already
+	// reported and is not reported again once the resources are checked
+	beta := schema.GroupVersionResource{Group: "beta.monitoring.platform-mesh.io", Version: "v1", Resource: "servicemonitorconfigurations"}
+	_, collides := info.Types.Register(beta, "servicemonitorconfiguration")
+	assert.False(t, collides)
+
+	gamma := schema.GroupVersionResource{Group: "gamma.monitoring.platform-mesh.io", Version: "v1", Resource: "servicemonitorconfigurations"}
+	collision, collides := info.Types.Register(gamma, "servicemonitorconfiguration")
+	assert.True(t, collides)
+	assert.Equal(t, "alpha.monitoring.platform-mesh.io", collision.Existing.Group)
+}
+
+func TestClusterCache_StoreResync(t *testing.T) {
+	cl := mocks.NewCluster(t)
+	k8sClient := mocks.NewClient(t)
+	mgr := mocks.NewManager(t)
+	orgsCluster := mocks.NewCluster(t)
+	orgsClient := mocks.NewClient(t)
+
+	cl.EXPECT().GetClient().Return(k8sClient)
+	cl.EXPECT().GetConfig().Return(&rest.Config{Host: "https://example.com"})
+	k8sClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			lc := obj.(*unstructured.Unstructured)
+			lc.SetAnnotations(map[string]string{"kcp.io/path": "root:orgs:myorg"})
+			lc.Object["spec"] = map[string]any{"owner": map[string]any{"cluster": "parent-cluster"}}
+		}).
+		Return(nil)
+
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(orgsCluster, nil)
+	orgsCluster.EXPECT().GetClient().Return(orgsClient)
+	setupStoreGet(orgsClient, "myorg", "myorg-store-id")
+
+	cc, err := clustercache.New(mgr, clustercache.WithStoreResync(10*time.Millisecond))
+	assert.NoError(t, err)
+	assert.NoError(t, cc.Engage(t.Context(), multicluster.ClusterName("test-cluster"), cl))
+
+	info, _ := cc.Get(multicluster.ClusterName("test-cluster"))
+	assert.Equal(t, "myorg-store-id-model", info.AuthorizationModelID)
+
+	// the model is re-pinned on the Store
+	orgsClient.ExpectedCalls = nil
+	setupStoreGet(orgsClient, "myorg", "repinned")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, cc.Start(ctx))
+	}()
+
+	assert.Eventually(t, func() bool {
+		info, _ := cc.Get(multicluster.ClusterName("test-cluster"))
+		return info.AuthorizationModelID == "repinned-model"
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestClusterCache_ReEngage(t *testing.T) {
+	cl := mocks.NewCluster(t)
+	k8sClient := mocks.NewClient(t)
+	mgr := mocks.NewManager(t)
+	orgsCluster := mocks.NewCluster(t)
+	orgsClient := mocks.NewClient(t)
+
+	cl.EXPECT().GetClient().Return(k8sClient)
+	cl.EXPECT().GetConfig().Return(&rest.Config{Host: "https://example.com"})
+	k8sClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			lc := obj.(*unstructured.Unstructured)
+			lc.SetAnnotations(map[string]string{"kcp.io/path": "root:orgs:myorg"})
+			lc.Object["spec"] = map[string]any{"owner": map[string]any{"cluster": "parent-cluster"}}
+		}).
+		Return(nil).
+		Once()
+
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(orgsCluster, nil)
+	orgsCluster.EXPECT().GetClient().Return(orgsClient)
+	setupStoreGet(orgsClient, "myorg", "myorg-store-id")
+
+	cc, err := clustercache.New(mgr)
+	assert.NoError(t, err)
+	assert.NoError(t, cc.Engage(t.Context(), multicluster.ClusterName("test-cluster"), cl))
+
+	// engaging the cluster again fails after the info was cached
+	ctx, cancel := context.WithCancel(t.Context())
+	k8sClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			_, found := cc.Get(multicluster.ClusterName("test-cluster"))
+			assert.True(t, found, "cluster hidden while engaging")
+			cancel()
+		}).
+		Return(errors.New("connection refused"))
+
+	assert.Error(t, cc.Engage(ctx, multicluster.ClusterName("test-cluster"), cl))
+
+	state, err := cc.State(multicluster.ClusterName("test-cluster"))
+	assert.Equal(t, clustercache.StateReady, state)
+	assert.NoError(t, err)
+	info, found := cc.Get(multicluster.ClusterName("test-cluster"))
+	assert.True(t, found)
+	assert.Equal(t, "myorg-store-id", info.StoreID)
+}
+
+func TestClusterCache_Paths(t *testing.T) {
+	cl := mocks.NewCluster(t)
+	k8sClient := mocks.NewClient(t)
+	cl.EXPECT().GetClient().Return(k8sClient)
+
+	path := "root:users:alice"
+	k8sClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			obj.(*unstructured.Unstructured).SetAnnotations(map[string]string{"kcp.io/path": path})
+		}).
+		Return(nil)
+
+	cc, err := clustercache.New(mocks.NewManager(t))
+	assert.NoError(t, err)
+
+	first, cancelFirst := context.WithCancel(t.Context())
+	defer cancelFirst()
+	assert.NoError(t, cc.Engage(first, multicluster.ClusterName("test-cluster"), cl))
+
+	name, found := cc.ClusterName("root:users:alice")
+	assert.True(t, found)
+	assert.Equal(t, multicluster.ClusterName("test-cluster"), name)
+
+	// the workspace is moved and the cluster engaged again
+	path = "root:users:bob"
+	second, cancelSecond := context.WithCancel(t.Context())
+	assert.NoError(t, cc.Engage(second, multicluster.ClusterName("test-cluster"), cl))
+	cancelFirst()
+
+	_, found = cc.ClusterName("root:users:alice")
+	assert.False(t, found)
+	_, found = cc.ClusterName("root:users:bob")
+	assert.True(t, found)
+
+	// the cluster is disengaged
+	cancelSecond()
+	assert.Eventually(t, func() bool {
+		_, found := cc.ClusterName("root:users:bob")
+		return !found
+	}, time.Second, 5*time.Millisecond)
+}
+
 func TestClusterCache_Get_NotFound(t *testing.T) {
 	mgr := mocks.NewManager(t)
 	cc, err := clustercache.New(mgr)
@@ -142,12 +393,19 @@ func TestClusterCache_Get_NotFound(t *testing.T) {
 	info, found := cc.Get(multicluster.ClusterName("non-existing"))
 	assert.False(t, found)
 	assert.Empty(t, info.StoreID)
+
+	state, err := cc.State(multicluster.ClusterName("non-existing"))
+	assert.Equal(t, clustercache.StateUnknown, state)
+	assert.NoError(t, err)
 }
 
 func setupStoreGet(c *mocks.Client, orgName, storeID string) {
 	c.EXPECT().Get(mock.Anything, types.NamespacedName{Name: orgName}, mock.Anything, mock.Anything).
 		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
 			obj.(*unstructured.Unstructured).Object = map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]any{openfga.AuthorizationModelIDAnnotation: storeID + "-model"},
+				},
 				"status": map[string]any{"storeId": storeID},
 			}
 		}).
@@ -161,5 +419,6 @@ func setupStoreGetMissing(c *mocks.Client, orgName string) {
 				"status": map[string]any{},
 			}
 		}).
-		Return(nil)
+		Return(nil).
+		Once()
 }
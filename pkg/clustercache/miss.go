@@ -0,0 +1,34 @@
+package clustercache
+
+import (
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/retry"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+// CacheMiss answers requests for a cluster which is not ready in cache.
+// Ignored and failed clusters get no opinion. Other clusters may still be
+// engaged and are retried after retryAfter as long as tracker allows it.
+func CacheMiss(cache Provider, tracker retry.Tracker[string], retryAfter time.Duration, clusterName string) authorization.Response {
+	switch state, err := cache.State(multicluster.ClusterName(clusterName)); state {
+	case StateIgnored:
+		klog.V(5).InfoS("cluster is not part of an organization, skipping", "clusterName", clusterName)
+		return authorization.NoOpinion()
+	case StateFailed:
+		klog.V(2).ErrorS(err, "cluster failed to engage, skipping", "clusterName", clusterName)
+		return authorization.NoOpinion()
+	}
+
+	if tracker != nil && tracker.ShouldRetry(clusterName) {
+		klog.V(5).InfoS("cluster not found in cache, retrying", "clusterName", clusterName)
+		tracker.Retried(clusterName)
+		return authorization.Retry(retryAfter)
+	}
+
+	klog.V(5).InfoS("cluster not found in cache", "clusterName", clusterName)
+	return authorization.NoOpinion()
+}
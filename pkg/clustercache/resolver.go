@@ -0,0 +1,61 @@
+package clustercache
+
+import (
+	"strings"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+// Resolver determines the logical cluster of a request from Extra keys tried
+// in order. Values which are workspace paths, e.g. root:orgs:acme, are
+// resolved to the name of their logical cluster through the cluster cache.
+type Resolver struct {
+	cache Provider
+	keys  []string
+}
+
+// NewResolver returns a Resolver trying keys in order. Workspace paths are
+// not resolved if cache is nil.
+func NewResolver(cache Provider, keys ...string) Resolver {
+	return Resolver{cache: cache, keys: keys}
+}
+
+// ClusterName returns the logical cluster name of the first key set in extra
+// whose value is a cluster name or a resolvable workspace path.
+func (r Resolver) ClusterName(extra map[string]authzv1.ExtraValue) (string, bool) {
+	for _, key := range r.keys {
+		values := extra[key]
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		value := values[0]
+
+		// Logical cluster names never contain colons, workspace paths
+		// below root always do.
+		if !strings.Contains(value, ":") {
+			return value, true
+		}
+		if r.cache == nil {
+			continue
+		}
+		name, ok := r.cache.ClusterName(value)
+		if !ok {
+			klog.V(5).InfoS("workspace path not found in cluster cache, trying next key", "key", key, "path", value)
+			continue
+		}
+		return string(name), true
+	}
+	return "", false
+}
+
+// Path returns the first workspace path set in extra, whether or not it can
+// be resolved.
+func (r Resolver) Path(extra map[string]authzv1.ExtraValue) (string, bool) {
+	for _, key := range r.keys {
+		if values := extra[key]; len(values) > 0 && strings.Contains(values[0], ":") {
+			return values[0], true
+		}
+	}
+	return "", false
+}
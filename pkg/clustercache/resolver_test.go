@@ -0,0 +1,86 @@
+package clustercache_test
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/stretchr/testify/assert"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const (
+	clusterKey     = "authorization.kubernetes.io/cluster-name"
+	fallbackKey    = "authentication.kubernetes.io/cluster-name"
+	workspacePath  = "root:orgs:acme:team"
+	workspaceID    = "2x7fa1b3c"
+	unknownPath    = "root:orgs:acme:unknown"
+	anotherCluster = "other-cluster"
+)
+
+func TestResolver_ClusterName(t *testing.T) {
+	testCases := []struct {
+		name  string
+		extra map[string]authzv1.ExtraValue
+		want  string
+		found bool
+	}{
+		{
+			name:  "should use the first key",
+			extra: map[string]authzv1.ExtraValue{clusterKey: {workspaceID}, fallbackKey: {anotherCluster}},
+			want:  workspaceID,
+			found: true,
+		},
+		{
+			name:  "should fall back to later keys",
+			extra: map[string]authzv1.ExtraValue{clusterKey: {""}, fallbackKey: {anotherCluster}},
+			want:  anotherCluster,
+			found: true,
+		},
+		{
+			name:  "should resolve workspace paths",
+			extra: map[string]authzv1.ExtraValue{clusterKey: {workspacePath}},
+			want:  workspaceID,
+			found: true,
+		},
+		{
+			name:  "should fall back if a workspace path is unknown",
+			extra: map[string]authzv1.ExtraValue{clusterKey: {unknownPath}, fallbackKey: {anotherCluster}},
+			want:  anotherCluster,
+			found: true,
+		},
+		{
+			name: "should not find a cluster without keys",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().ClusterName(workspacePath).Return(multicluster.ClusterName(workspaceID), true).Maybe()
+			cc.EXPECT().ClusterName(unknownPath).Return("", false).Maybe()
+
+			name, found := clustercache.NewResolver(cc, clusterKey, fallbackKey).ClusterName(tc.extra)
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.want, name)
+		})
+	}
+}
+
+func TestResolver_ClusterName_WithoutCache(t *testing.T) {
+	_, found := clustercache.NewResolver(nil, clusterKey).ClusterName(map[string]authzv1.ExtraValue{clusterKey: {workspacePath}})
+	assert.False(t, found)
+}
+
+func TestResolver_Path(t *testing.T) {
+	resolver := clustercache.NewResolver(nil, clusterKey, fallbackKey)
+
+	path, found := resolver.Path(map[string]authzv1.ExtraValue{clusterKey: {workspaceID}, fallbackKey: {unknownPath}})
+	assert.True(t, found)
+	assert.Equal(t, unknownPath, path)
+
+	_, found = resolver.Path(map[string]authzv1.ExtraValue{clusterKey: {workspaceID}})
+	assert.False(t, found)
+}
@@ -10,6 +10,9 @@ type WebhookConfig struct {
 	CertDir                    string
 	ClusterKey                 string
 	AllowedNonResourcePrefixes []string
+	// FallbackClusterKeys are Extra keys tried in order if ClusterKey is not
+	// set. Values which are workspace paths are resolved to logical clusters.
+	FallbackClusterKeys []string
 
 	// CacheMissMaxRetries is the maximum number of retries per key before stopping.
 	CacheMissMaxRetries uint
@@ -19,6 +22,95 @@ type WebhookConfig struct {
 	CacheMissCleanupInterval time.Duration
 	// CacheMissRetryAfter is the delay before retrying on cache miss.
 	CacheMissRetryAfter time.Duration
+	// StoreResyncInterval is the interval at which the Store objects of
+	// cached orgs are re-read to pick up pinned authorization models.
+	StoreResyncInterval time.Duration
+
+	// SubresourcesInheritingVerb lists subresources that are checked with the
+	// verb of their parent resource instead of a <verb>_<subresource> relation.
+	SubresourcesInheritingVerb []string
+	// VerbAliases checks verbs with the relation of another verb, e.g. patch=update.
+	VerbAliases map[string]string
+	// UnknownVerbPolicy configures how verbs unknown to the webhook are
+	// checked: object, parent or skip.
+	UnknownVerbPolicy string
+
+	// OwnerReferencesDepth bounds how many levels of owner references are
+	// followed to parent objects to their owners. Disabled if zero.
+	OwnerReferencesDepth int
+	// OwnerReferenceKinds lists the owner kinds followed, as Kind.group.
+	OwnerReferenceKinds []string
+
+	// GroupsInclude and GroupsExclude select the request groups that are sent
+	// to OpenFGA as contextual group memberships.
+	GroupsInclude []string
+	GroupsExclude []string
+
+	// TypeNaming configures how groups exceeding the relation length limit
+	// are shortened: truncate, hashed or truncate-all-verbs.
+	TypeNaming string
+	// DetectTypeCollisions logs resources served by a cluster which map to
+	// the same OpenFGA object type. The resources are discovered when the
+	// cluster is engaged.
+	DetectTypeCollisions bool
+
+	// MappingFile is a YAML file with templates mapping requests to OpenFGA
+	// objects, relations and contextual tuples. The built-in mapping is used
+	// if empty.
+	MappingFile string
+	// EnforceScopes denies requests of identities whose kcp scopes do not
+	// include the cluster of the request.
+	EnforceScopes bool
+	// BindPermissionClaims is how binds are answered whose user may not grant
+	// the permission claims of the APIExport: ignore, noopinion or deny.
+	BindPermissionClaims string
+	// BindUser is how the check of the user binding an APIExport is combined
+	// with the check of the APIExport: ignore, and or or.
+	BindUser string
+}
+
+type OrgsStoreConfig struct {
+	// ClusterName is the workspace containing the Store object of the orgs store.
+	ClusterName string
+	// Name is the name of the Store object of the orgs store.
+	Name string
+	// ResyncInterval is the interval at which the Store object is re-read.
+	ResyncInterval time.Duration
+}
+
+type IdentityConfig struct {
+	// Source selects the attribute identifying a user: username, uid or
+	// extra:<key>.
+	Source string
+	// PrefixReplacements replaces username prefixes, e.g. oidc: with nothing.
+	PrefixReplacements map[string]string
+	// Lowercase lowercases usernames.
+	Lowercase bool
+	// Types maps username prefixes to the OpenFGA type of their users.
+	Types map[string]string
+}
+
+type BreakGlassConfig struct {
+	// ClusterName, Namespace and Name locate the ConfigMap holding the
+	// break-glass grants. Break-glass access is disabled if Name is empty.
+	ClusterName string
+	Namespace   string
+	Name        string
+	// ResyncInterval is the interval at which the grants of the watched
+	// ConfigMap are re-parsed.
+	ResyncInterval time.Duration
+}
+
+type BypassConfig struct {
+	// Users and Groups are path.Match patterns of identities whose requests
+	// are answered without OpenFGA.
+	Users  []string
+	Groups []string
+	// Action is the response to bypassed requests: allow or noopinion.
+	Action string
+	// ServiceAccountClusters are the logical clusters whose service accounts
+	// may be bypassed.
+	ServiceAccountClusters []string
 }
 
 type Config struct {
@@ -26,7 +118,34 @@ type Config struct {
 	HealthProbeBindAddress string
 	OpenFGAAddr            string
 
-	Webhook WebhookConfig
+	// OpenFGAModelRefreshInterval is the interval after which the latest
+	// authorization model of a store is resolved again.
+	OpenFGAModelRefreshInterval time.Duration
+	// OpenFGAAuthorizationModelIDs pins the authorization model per store ID.
+	OpenFGAAuthorizationModelIDs map[string]string
+	// OpenFGAValidateRelations skips checks for types and relations that are
+	// not defined in the store's authorization model.
+	OpenFGAValidateRelations bool
+	// OpenFGAConsistency maps verbs, or "*" for any other verb, to the
+	// consistency preference of their checks.
+	OpenFGAConsistency map[string]string
+	// OpenFGADenyRelationFormat derives the deny relation checked before a
+	// relation, e.g. deny_%s. Deny relations are not checked if empty.
+	OpenFGADenyRelationFormat string
+	// OpenFGACheckContext passes request attributes as context to OpenFGA conditions.
+	OpenFGACheckContext bool
+	// OpenFGACheckContextExtraKeys lists the Extra keys passed in the check context.
+	OpenFGACheckContextExtraKeys []string
+
+	// PolicyFile is a YAML file with CEL rules allowing or denying requests
+	// before OpenFGA is checked.
+	PolicyFile string
+
+	Webhook    WebhookConfig
+	OrgsStore  OrgsStoreConfig
+	Identity   IdentityConfig
+	Bypass     BypassConfig
+	BreakGlass BreakGlassConfig
 
 	APIExportEndpointSliceName string
 }
@@ -36,6 +155,12 @@ func New() *Config {
 		MetricsBindAddress:     ":9090",
 		HealthProbeBindAddress: ":8090",
 		OpenFGAAddr:            "openfga.platform-mesh-system:8081",
+
+		OpenFGAModelRefreshInterval:  5 * time.Minute,
+		OpenFGAAuthorizationModelIDs: map[string]string{},
+		OpenFGAValidateRelations:     true,
+		OpenFGAConsistency:           map[string]string{},
+		OpenFGACheckContext:          true,
 		Webhook: WebhookConfig{
 			CertDir:                    "config",
 			ClusterKey:                 "authorization.kubernetes.io/cluster-name",
@@ -44,6 +169,35 @@ func New() *Config {
 			CacheMissTTL:               5 * time.Minute,
 			CacheMissCleanupInterval:   2 * time.Minute,
 			CacheMissRetryAfter:        1 * time.Second,
+			StoreResyncInterval:        30 * time.Second,
+			VerbAliases:                map[string]string{},
+			UnknownVerbPolicy:          "object",
+			OwnerReferenceKinds:        []string{"ReplicaSet.apps", "Deployment.apps", "StatefulSet.apps", "DaemonSet.apps", "Job.batch", "CronJob.batch"},
+			GroupsInclude:              []string{"*"},
+			GroupsExclude:              []string{"system:*"},
+			TypeNaming:                 "truncate",
+			DetectTypeCollisions:       true,
+			EnforceScopes:              true,
+			BindPermissionClaims:       "ignore",
+			BindUser:                   "ignore",
+		},
+		OrgsStore: OrgsStoreConfig{
+			ClusterName:    "root:orgs",
+			Name:           "orgs",
+			ResyncInterval: 30 * time.Second,
+		},
+		Identity: IdentityConfig{
+			Source:             "username",
+			PrefixReplacements: map[string]string{},
+			Types:              map[string]string{},
+		},
+		Bypass: BypassConfig{
+			Action: "allow",
+		},
+		BreakGlass: BreakGlassConfig{
+			ClusterName:    "root",
+			Namespace:      "platform-mesh-system",
+			ResyncInterval: 10 * time.Second,
 		},
 
 		APIExportEndpointSliceName: "core.platform-mesh.io",
@@ -54,12 +208,50 @@ func (cfg *Config) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&cfg.MetricsBindAddress, "metrics-bind-address", cfg.MetricsBindAddress, "Set the metrics bind address")
 	fs.StringVar(&cfg.HealthProbeBindAddress, "health-probe-bind-address", cfg.HealthProbeBindAddress, "Set the health probe bind address")
 	fs.StringVar(&cfg.OpenFGAAddr, "openfga-addr", cfg.OpenFGAAddr, "Set the OpenFGA address")
+	fs.DurationVar(&cfg.OpenFGAModelRefreshInterval, "openfga-model-refresh-interval", cfg.OpenFGAModelRefreshInterval, "Interval after which the latest authorization model of a store is resolved again")
+	fs.StringToStringVar(&cfg.OpenFGAAuthorizationModelIDs, "openfga-authorization-model-ids", cfg.OpenFGAAuthorizationModelIDs, "Authorization model IDs pinned per store ID, e.g. <store-id>=<model-id>")
+	fs.BoolVar(&cfg.OpenFGAValidateRelations, "openfga-validate-relations", cfg.OpenFGAValidateRelations, "Skip checks for types and relations not defined in the store's authorization model")
+	fs.StringToStringVar(&cfg.OpenFGAConsistency, "openfga-consistency", cfg.OpenFGAConsistency, "Consistency preference of checks per verb, e.g. create=HIGHER_CONSISTENCY,*=MINIMIZE_LATENCY")
+	fs.StringVar(&cfg.OpenFGADenyRelationFormat, "openfga-deny-relation-format", cfg.OpenFGADenyRelationFormat, "Format of the deny relation checked before a relation, e.g. deny_%s; disabled if empty")
+	fs.BoolVar(&cfg.OpenFGACheckContext, "openfga-check-context", cfg.OpenFGACheckContext, "Pass request attributes as context to OpenFGA conditions")
+	fs.StringSliceVar(&cfg.OpenFGACheckContextExtraKeys, "openfga-check-context-extra-keys", cfg.OpenFGACheckContextExtraKeys, "Extra keys of the request passed in the OpenFGA check context")
+	fs.StringVar(&cfg.PolicyFile, "policy-file", cfg.PolicyFile, "YAML file with CEL rules allowing or denying requests before OpenFGA is checked")
 	fs.StringVar(&cfg.Webhook.CertDir, "webhook-cert-dir", cfg.Webhook.CertDir, "Set the webhook certificate directory")
 	fs.StringVar(&cfg.Webhook.ClusterKey, "webhook-cluster-key", cfg.Webhook.ClusterKey, "Set the webhook cluster key")
+	fs.StringSliceVar(&cfg.Webhook.FallbackClusterKeys, "webhook-fallback-cluster-keys", cfg.Webhook.FallbackClusterKeys, "Extra keys tried in order if the webhook cluster key is not set; workspace paths are resolved to logical clusters")
 	fs.StringSliceVar(&cfg.Webhook.AllowedNonResourcePrefixes, "webhook-allowed-nonresource-prefixes", cfg.Webhook.AllowedNonResourcePrefixes, "Set the allowed non-resource prefixes for the webhook")
 	fs.UintVar(&cfg.Webhook.CacheMissMaxRetries, "webhook-cache-miss-max-retries", cfg.Webhook.CacheMissMaxRetries, "Maximum number of retries per cluster on cache miss")
 	fs.DurationVar(&cfg.Webhook.CacheMissTTL, "webhook-cache-miss-ttl", cfg.Webhook.CacheMissTTL, "Duration after which cache miss count resets for a cluster")
 	fs.DurationVar(&cfg.Webhook.CacheMissCleanupInterval, "webhook-cache-miss-cleanup-interval", cfg.Webhook.CacheMissCleanupInterval, "Interval at which cache miss keys are checked for expiration")
+	fs.DurationVar(&cfg.Webhook.StoreResyncInterval, "webhook-store-resync-interval", cfg.Webhook.StoreResyncInterval, "Interval at which the Store objects of cached orgs are re-read to pick up pinned authorization models; disabled if 0")
 	fs.DurationVar(&cfg.Webhook.CacheMissRetryAfter, "webhook-cache-miss-retry-after", cfg.Webhook.CacheMissRetryAfter, "Delay before retrying on cache miss")
+	fs.StringSliceVar(&cfg.Webhook.SubresourcesInheritingVerb, "webhook-subresources-inheriting-verb", cfg.Webhook.SubresourcesInheritingVerb, "Subresources that are checked with the verb of their parent resource")
+	fs.StringToStringVar(&cfg.Webhook.VerbAliases, "webhook-verb-aliases", cfg.Webhook.VerbAliases, "Verbs checked with the relation of another verb, e.g. patch=update")
+	fs.StringVar(&cfg.Webhook.UnknownVerbPolicy, "webhook-unknown-verb-policy", cfg.Webhook.UnknownVerbPolicy, "How verbs unknown to the webhook are checked: object, parent or skip")
+	fs.IntVar(&cfg.Webhook.OwnerReferencesDepth, "webhook-owner-references-depth", cfg.Webhook.OwnerReferencesDepth, "Levels of owner references followed to parent objects to their owners; disabled if 0")
+	fs.StringSliceVar(&cfg.Webhook.OwnerReferenceKinds, "webhook-owner-reference-kinds", cfg.Webhook.OwnerReferenceKinds, "Owner kinds followed as parents, as Kind.group")
+	fs.StringSliceVar(&cfg.Webhook.GroupsInclude, "webhook-groups-include", cfg.Webhook.GroupsInclude, "Patterns of request groups sent to OpenFGA as contextual group memberships")
+	fs.StringSliceVar(&cfg.Webhook.GroupsExclude, "webhook-groups-exclude", cfg.Webhook.GroupsExclude, "Patterns of request groups never sent to OpenFGA as contextual group memberships")
+	fs.StringVar(&cfg.Webhook.TypeNaming, "webhook-type-naming", cfg.Webhook.TypeNaming, "How groups exceeding the relation length limit are shortened: truncate, hashed or truncate-all-verbs")
+	fs.BoolVar(&cfg.Webhook.DetectTypeCollisions, "webhook-detect-type-collisions", cfg.Webhook.DetectTypeCollisions, "Log resources served by a cluster which map to the same OpenFGA object type; discovers the resources of every engaged cluster")
+	fs.BoolVar(&cfg.Webhook.EnforceScopes, "webhook-enforce-scopes", cfg.Webhook.EnforceScopes, "Deny requests of identities whose kcp scopes do not include the cluster of the request")
+	fs.StringVar(&cfg.Webhook.BindPermissionClaims, "webhook-bind-permission-claims", cfg.Webhook.BindPermissionClaims, "How APIExport binds are answered whose user may not grant the permission claims of the export: ignore, noopinion or deny")
+	fs.StringVar(&cfg.Webhook.BindUser, "webhook-bind-user", cfg.Webhook.BindUser, "How the check of the user binding an APIExport is combined with the check of the export: ignore, and or or")
+	fs.StringVar(&cfg.Webhook.MappingFile, "webhook-mapping-file", cfg.Webhook.MappingFile, "YAML file with templates mapping requests to OpenFGA objects, relations and contextual tuples per resource")
+	fs.StringVar(&cfg.OrgsStore.ClusterName, "orgs-store-cluster", cfg.OrgsStore.ClusterName, "Workspace checked against the orgs OpenFGA store, containing its Store object")
+	fs.StringVar(&cfg.OrgsStore.Name, "orgs-store-name", cfg.OrgsStore.Name, "Name of the Store object of the orgs OpenFGA store")
+	fs.DurationVar(&cfg.OrgsStore.ResyncInterval, "orgs-store-resync-interval", cfg.OrgsStore.ResyncInterval, "Interval at which the orgs Store object is re-read to follow store rotation")
+	fs.StringVar(&cfg.Identity.Source, "identity-source", cfg.Identity.Source, "Attribute identifying users in OpenFGA: username, uid or extra:<key>")
+	fs.StringToStringVar(&cfg.Identity.PrefixReplacements, "identity-prefix-replacements", cfg.Identity.PrefixReplacements, "Username prefixes to replace, e.g. oidc:= to strip the oidc: prefix")
+	fs.BoolVar(&cfg.Identity.Lowercase, "identity-lowercase", cfg.Identity.Lowercase, "Lowercase usernames before checking them in OpenFGA")
+	fs.StringToStringVar(&cfg.Identity.Types, "identity-types", cfg.Identity.Types, "OpenFGA user type per username prefix, e.g. partner:=partner_user")
+	fs.StringSliceVar(&cfg.Bypass.Users, "bypass-users", cfg.Bypass.Users, "Patterns of users whose requests are answered without OpenFGA, e.g. system:kcp:*")
+	fs.StringSliceVar(&cfg.Bypass.Groups, "bypass-groups", cfg.Bypass.Groups, "Patterns of groups whose members' requests are answered without OpenFGA, e.g. system:masters")
+	fs.StringSliceVar(&cfg.Bypass.ServiceAccountClusters, "bypass-service-account-clusters", cfg.Bypass.ServiceAccountClusters, "Logical clusters whose service accounts may be bypassed; service accounts of other clusters are never bypassed")
+	fs.StringVar(&cfg.Bypass.Action, "bypass-action", cfg.Bypass.Action, "Response to bypassed requests: allow, or noopinion to leave the decision to other authorizers")
+	fs.StringVar(&cfg.BreakGlass.ClusterName, "break-glass-cluster", cfg.BreakGlass.ClusterName, "Workspace containing the ConfigMap of break-glass grants")
+	fs.StringVar(&cfg.BreakGlass.Namespace, "break-glass-namespace", cfg.BreakGlass.Namespace, "Namespace of the ConfigMap of break-glass grants")
+	fs.StringVar(&cfg.BreakGlass.Name, "break-glass-configmap", cfg.BreakGlass.Name, "Name of the ConfigMap of break-glass grants; break-glass access is disabled if empty")
+	fs.DurationVar(&cfg.BreakGlass.ResyncInterval, "break-glass-resync-interval", cfg.BreakGlass.ResyncInterval, "Interval at which the grants of the watched break-glass ConfigMap are re-parsed")
 	fs.StringVar(&cfg.APIExportEndpointSliceName, "kcp-api-export-endpoint-slice-name", cfg.APIExportEndpointSliceName, "Set the KCP API export endpoint slice name")
 }
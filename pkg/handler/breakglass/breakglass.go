@@ -0,0 +1,113 @@
+package breakglass
+
+import (
+	"context"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/breakglass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const (
+	decisionAllowed = "allowed"
+	decisionExpired = "expired"
+)
+
+type breakGlassAuthorizer struct {
+	grants       breakglass.Provider
+	clusterCache clustercache.Provider
+}
+
+var _ authorization.Handler = &breakGlassAuthorizer{}
+
+// New returns a handler allowing requests covered by an unexpired break-glass
+// grant. Every request matching a grant is audit logged.
+func New(grants breakglass.Provider, clusterCache clustercache.Provider) authorization.Handler {
+	return &breakGlassAuthorizer{
+		grants:       grants,
+		clusterCache: clusterCache,
+	}
+}
+
+func (b *breakGlassAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
+	klog.V(5).Info("handling request in BreakGlassAuthorizer")
+
+	grants := b.grants.Grants()
+	if len(grants) == 0 {
+		return authorization.NoOpinion()
+	}
+
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" {
+		klog.V(5).Info("request does not contain a known cluster, skipping")
+		return authorization.NoOpinion()
+	}
+
+	orgName := ""
+	if info, ok := b.clusterCache.Get(multicluster.ClusterName(clusterName)); ok {
+		orgName = info.OrgName
+	}
+
+	nonResourcePath := ""
+	if !req.Attributes.ResourceRequest {
+		nonResourcePath = req.Attributes.Path
+	}
+
+	now := time.Now()
+	var expired *breakglass.Grant
+	for i, grant := range grants {
+		if !grant.Matches(req.Attributes.User, req.Attributes.Groups, clusterName, orgName, req.Attributes.Verb, nonResourcePath) {
+			continue
+		}
+		if grant.Expired(now) {
+			if expired == nil {
+				expired = &grants[i]
+			}
+			continue
+		}
+
+		audit(req, grant, decisionAllowed, clusterName, orgName)
+		return authorization.Allowed()
+	}
+
+	if expired != nil {
+		audit(req, *expired, decisionExpired, clusterName, orgName)
+	}
+	return authorization.NoOpinion()
+}
+
+// audit logs a break-glass decision unconditionally, so that every use of a
+// grant can be reconstructed from the logs.
+func audit(req authorization.Request, grant breakglass.Grant, decision, clusterName, orgName string) {
+	metrics.RecordBreakGlass(grant.Name, decision)
+
+	keysAndValues := []any{
+		"decision", decision,
+		"grant", grant.Name,
+		"justification", grant.Justification,
+		"expires", grant.Expires,
+		"user", req.Spec.User,
+		"groups", req.Spec.Groups,
+		"clusterName", clusterName,
+		"orgName", orgName,
+	}
+	if attrs := req.Spec.ResourceAttributes; attrs != nil {
+		keysAndValues = append(keysAndValues,
+			"verb", attrs.Verb,
+			"group", attrs.Group,
+			"resource", attrs.Resource,
+			"subresource", attrs.Subresource,
+			"namespace", attrs.Namespace,
+			"name", attrs.Name)
+	}
+	if attrs := req.Spec.NonResourceAttributes; attrs != nil {
+		keysAndValues = append(keysAndValues, "verb", attrs.Verb, "path", attrs.Path)
+	}
+
+	klog.InfoS("BREAK-GLASS AUDIT", keysAndValues...)
+}
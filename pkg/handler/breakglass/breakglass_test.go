@@ -0,0 +1,155 @@
+package breakglass_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/breakglass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	handler "github.com/platform-mesh/rebac-authz-webhook/pkg/handler/breakglass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const clusterKey = "authorization.kubernetes.io/cluster-name"
+
+func TestHandler(t *testing.T) {
+	active := breakglass.Grant{
+		Name:          "incident-42",
+		Users:         []string{"sre-alice"},
+		Orgs:          []string{"acme"},
+		Verbs:         []string{"get", "list"},
+		Expires:       time.Now().Add(time.Hour),
+		Justification: "INC-42",
+	}
+	expired := breakglass.Grant{
+		Name:          "incident-41",
+		Groups:        []string{"oncall"},
+		Clusters:      []string{"tenant-cluster"},
+		Verbs:         []string{breakglass.Any},
+		Expires:       time.Now().Add(-time.Hour),
+		Justification: "INC-41",
+	}
+
+	testCases := []struct {
+		name   string
+		grants []breakglass.Grant
+		spec   v1.SubjectAccessReviewSpec
+		res    authorization.Response
+		// grant and decision are the expected audit entry, if any
+		grant    string
+		decision string
+	}{
+		{
+			name:   "should allow requests covered by a grant",
+			grants: []breakglass.Grant{expired, active},
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "sre-alice",
+				Extra:              map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "secrets", Namespace: "default", Name: "db"},
+			},
+			res:      authorization.Allowed(),
+			grant:    "incident-42",
+			decision: "allowed",
+		},
+		{
+			name:   "should not allow requests covered by an expired grant",
+			grants: []breakglass.Grant{expired, active},
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "bob",
+				Groups:             []string{"oncall"},
+				Extra:              map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "delete", Resource: "pods", Namespace: "default", Name: "web"},
+			},
+			res:      authorization.NoOpinion(),
+			grant:    "incident-41",
+			decision: "expired",
+		},
+		{
+			name:   "should not allow verbs missing from the grant",
+			grants: []breakglass.Grant{active},
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "sre-alice",
+				Extra:              map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "delete", Resource: "secrets", Namespace: "default", Name: "db"},
+			},
+			res: authorization.NoOpinion(),
+		},
+		{
+			name:   "should not allow non-resource requests with grants of resources",
+			grants: []breakglass.Grant{active},
+			spec: v1.SubjectAccessReviewSpec{
+				User:                  "sre-alice",
+				Extra:                 map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				NonResourceAttributes: &v1.NonResourceAttributes{Verb: "get", Path: "/metrics"},
+			},
+			res: authorization.NoOpinion(),
+		},
+		{
+			name: "should allow non-resource requests covered by a grant",
+			grants: []breakglass.Grant{func() breakglass.Grant {
+				grant := active
+				grant.NonResourceURLs = []string{"/metrics"}
+				return grant
+			}()},
+			spec: v1.SubjectAccessReviewSpec{
+				User:                  "sre-alice",
+				Extra:                 map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				NonResourceAttributes: &v1.NonResourceAttributes{Verb: "get", Path: "/metrics"},
+			},
+			res:      authorization.Allowed(),
+			grant:    "incident-42",
+			decision: "allowed",
+		},
+		{
+			name:   "should return no opinion without cluster",
+			grants: []breakglass.Grant{active},
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "sre-alice",
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "secrets"},
+			},
+			res: authorization.NoOpinion(),
+		},
+		{
+			name: "should return no opinion without grants",
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "sre-alice",
+				Extra:              map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "secrets"},
+			},
+			res: authorization.NoOpinion(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics.BreakGlass.Reset()
+
+			grants := mocks.NewBreakGlassProvider(t)
+			grants.EXPECT().Grants().Return(tc.grants)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("tenant-cluster")).Return(clustercache.ClusterInfo{OrgName: "acme"}, true).Maybe()
+
+			h := handler.New(grants, cc)
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{Spec: tc.spec}, clustercache.NewResolver(nil, clusterKey))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+			assert.Equal(t, tc.res, res)
+
+			if tc.decision == "" {
+				assert.Equal(t, 0, testutil.CollectAndCount(metrics.BreakGlass))
+				return
+			}
+			assert.Equal(t, 1, testutil.CollectAndCount(metrics.BreakGlass))
+			assert.Equal(t, 1.0, testutil.ToFloat64(metrics.BreakGlass.WithLabelValues(tc.grant, tc.decision)))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package bypass
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+
+	"k8s.io/klog/v2"
+)
+
+// Action is the response to requests of bypassed identities.
+type Action string
+
+const (
+	// ActionAllow allows requests of bypassed identities.
+	ActionAllow Action = "allow"
+	// ActionNoOpinion stops the handler chain with no opinion, leaving the
+	// decision to the other authorizers of the apiserver.
+	ActionNoOpinion Action = "noopinion"
+)
+
+// ParseAction validates action.
+func ParseAction(action string) (Action, error) {
+	switch a := Action(action); a {
+	case ActionAllow, ActionNoOpinion:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown bypass action %q, expected %q or %q", action, ActionAllow, ActionNoOpinion)
+	}
+}
+
+type bypassAuthorizer struct {
+	users  []string
+	groups []string
+	action Action
+	// serviceAccountClusters are the logical clusters whose service accounts
+	// may be bypassed.
+	serviceAccountClusters map[string]bool
+}
+
+var _ authorization.Handler = &bypassAuthorizer{}
+
+// New returns a handler answering requests of users or groups matching any
+// of the path.Match patterns with action, before any OpenFGA work is done.
+// Service accounts of the same name exist in every logical cluster, so
+// service accounts are only bypassed if they belong to one of
+// serviceAccountClusters.
+func New(users, groups []string, action Action, serviceAccountClusters []string) (authorization.Handler, error) {
+	for _, pattern := range append(append([]string{}, users...), groups...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid bypass pattern %q: %w", pattern, err)
+		}
+	}
+	b := &bypassAuthorizer{
+		users:                  users,
+		groups:                 groups,
+		action:                 action,
+		serviceAccountClusters: map[string]bool{},
+	}
+	for _, cluster := range serviceAccountClusters {
+		b.serviceAccountClusters[cluster] = true
+	}
+	return b, nil
+}
+
+func (b *bypassAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
+	klog.V(5).Info("handling request in BypassAuthorizer")
+
+	pattern, ok := b.match(req.Spec.User, req.Spec.Groups)
+	if !ok {
+		return authorization.NoOpinion()
+	}
+
+	if sa, ok := identity.ParseServiceAccount(req.Spec, ""); ok && !b.serviceAccountClusters[sa.ClusterName] {
+		klog.V(5).InfoS("service account of other cluster matches bypass pattern, skipping", "user", req.Spec.User, "cluster", sa.ClusterName, "pattern", pattern)
+		return authorization.NoOpinion()
+	}
+
+	klog.V(5).InfoS("request of system identity bypasses OpenFGA", "user", req.Spec.User, "pattern", pattern, "action", b.action)
+	metrics.RecordBypass(string(b.action))
+
+	if b.action == ActionAllow {
+		return authorization.Allowed()
+	}
+	return authorization.Aborted()
+}
+
+// match returns the first pattern matching user or one of groups.
+func (b *bypassAuthorizer) match(user string, groups []string) (string, bool) {
+	for _, pattern := range b.users {
+		if matched, _ := path.Match(pattern, user); matched {
+			return pattern, true
+		}
+	}
+	for _, pattern := range b.groups {
+		for _, group := range groups {
+			if matched, _ := path.Match(pattern, group); matched {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
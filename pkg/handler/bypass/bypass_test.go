@@ -0,0 +1,116 @@
+package bypass_test
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/bypass"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/authorization/v1"
+)
+
+func TestHandler(t *testing.T) {
+	users := []string{"system:kcp:*", "system:serviceaccount:kube-system:*"}
+	groups := []string{"system:masters"}
+
+	testCases := []struct {
+		name   string
+		action bypass.Action
+		user   string
+		groups []string
+		extra  map[string]v1.ExtraValue
+		res    authorization.Response
+		// bypassed is the expected increase of the bypass metric
+		bypassed float64
+	}{
+		{
+			name:     "should allow users matching a pattern",
+			action:   bypass.ActionAllow,
+			user:     "system:kcp:logical-cluster-admin",
+			res:      authorization.Allowed(),
+			bypassed: 1,
+		},
+		{
+			name:     "should allow members of a bypassed group",
+			action:   bypass.ActionAllow,
+			user:     "admin",
+			groups:   []string{"system:authenticated", "system:masters"},
+			res:      authorization.Allowed(),
+			bypassed: 1,
+		},
+		{
+			name:     "should stop the chain with no opinion",
+			action:   bypass.ActionNoOpinion,
+			user:     "system:serviceaccount:kube-system:namespace-controller",
+			extra:    map[string]v1.ExtraValue{"authentication.kubernetes.io/cluster-name": {"system"}},
+			res:      authorization.Aborted(),
+			bypassed: 1,
+		},
+		{
+			name:   "should not bypass service accounts of other clusters",
+			action: bypass.ActionAllow,
+			user:   "system:serviceaccount:kube-system:namespace-controller",
+			extra:  map[string]v1.ExtraValue{"authentication.kubernetes.io/cluster-name": {"tenant"}},
+			res:    authorization.NoOpinion(),
+		},
+		{
+			name:   "should not bypass service accounts without cluster",
+			action: bypass.ActionAllow,
+			user:   "system:serviceaccount:kube-system:namespace-controller",
+			res:    authorization.NoOpinion(),
+		},
+		{
+			name:   "should not bypass service accounts of other clusters by group",
+			action: bypass.ActionAllow,
+			user:   "system:serviceaccount:default:admin",
+			groups: []string{"system:serviceaccounts", "system:masters"},
+			extra:  map[string]v1.ExtraValue{"authentication.kubernetes.io/cluster-name": {"tenant"}},
+			res:    authorization.NoOpinion(),
+		},
+		{
+			name:   "should return no opinion for other identities",
+			action: bypass.ActionAllow,
+			user:   "alice",
+			groups: []string{"system:authenticated"},
+			res:    authorization.NoOpinion(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics.Bypassed.Reset()
+
+			h, err := bypass.New(users, groups, tc.action, []string{"system"})
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User:   tc.user,
+						Groups: tc.groups,
+						Extra:  tc.extra,
+					},
+				},
+			})
+			assert.Equal(t, tc.res, res)
+			assert.Equal(t, tc.bypassed, testutil.ToFloat64(metrics.Bypassed.WithLabelValues(string(tc.action))))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := bypass.New([]string{"system:["}, nil, bypass.ActionAllow, nil)
+	assert.Error(t, err)
+}
+
+func TestParseAction(t *testing.T) {
+	action, err := bypass.ParseAction("noopinion")
+	assert.NoError(t, err)
+	assert.Equal(t, bypass.ActionNoOpinion, action)
+
+	_, err = bypass.ParseAction("deny")
+	assert.Error(t, err)
+}
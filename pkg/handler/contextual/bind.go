@@ -0,0 +1,371 @@
+package contextual
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+// PermissionClaimPolicy configures how bind requests are answered whose user
+// may not grant the permission claims of the APIExport.
+type PermissionClaimPolicy string
+
+const (
+	// PermissionClaimsIgnore does not check permission claims.
+	PermissionClaimsIgnore PermissionClaimPolicy = "ignore"
+	// PermissionClaimsNoOpinion returns no opinion if a claim may not be granted.
+	PermissionClaimsNoOpinion PermissionClaimPolicy = "noopinion"
+	// PermissionClaimsDeny denies the bind if a claim may not be granted.
+	PermissionClaimsDeny PermissionClaimPolicy = "deny"
+)
+
+// ParsePermissionClaimPolicy validates policy.
+func ParsePermissionClaimPolicy(policy string) (PermissionClaimPolicy, error) {
+	switch p := PermissionClaimPolicy(policy); p {
+	case PermissionClaimsIgnore, PermissionClaimsNoOpinion, PermissionClaimsDeny:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown permission claim policy %q, expected one of %q, %q or %q", policy, PermissionClaimsIgnore, PermissionClaimsNoOpinion, PermissionClaimsDeny)
+	}
+}
+
+var apiExportGVK = schema.GroupVersionKind{
+	Group:   "apis.kcp.io",
+	Version: "v1alpha2",
+	Kind:    "APIExport",
+}
+
+// claimAllVerbs are the verbs granted by a permission claim for verb *.
+var claimAllVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+
+// permissionClaim is the part of an APIExport permission claim the bind
+// check needs.
+type permissionClaim struct {
+	Group    string   `json:"group,omitempty"`
+	Resource string   `json:"resource"`
+	Verbs    []string `json:"verbs"`
+}
+
+func (p permissionClaim) String() string {
+	return schema.GroupResource{Group: p.Group, Resource: p.Resource}.String()
+}
+
+// BindUserPolicy configures how the check of the user binding an APIExport is
+// combined with the check of the APIExport itself.
+type BindUserPolicy string
+
+const (
+	// BindUserIgnore only checks the APIExport.
+	BindUserIgnore BindUserPolicy = "ignore"
+	// BindUserAnd allows the bind if both the APIExport and the user may bind.
+	BindUserAnd BindUserPolicy = "and"
+	// BindUserOr allows the bind if the APIExport or the user may bind.
+	BindUserOr BindUserPolicy = "or"
+)
+
+// ParseBindUserPolicy validates policy.
+func ParseBindUserPolicy(policy string) (BindUserPolicy, error) {
+	switch p := BindUserPolicy(policy); p {
+	case BindUserIgnore, BindUserAnd, BindUserOr:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown bind user policy %q, expected one of %q, %q or %q", policy, BindUserIgnore, BindUserAnd, BindUserOr)
+	}
+}
+
+// apiBindings is the resource whose collection relation on the consumer
+// account a user needs to bind APIExports.
+var apiBindings = schema.GroupResource{Group: "apis.kcp.io", Resource: "apibindings"}
+
+// bindUserCheck returns the check whether the user of req may create
+// APIBindings in the consumer account, e.g. create_apis_kcp_io_apibindings.
+// It is sent along with bind, the check of the APIExport.
+func (c *contextualAuthorizer) bindUserCheck(ctx context.Context, req authorization.Request, bind *openfgav1.CheckRequest, consumerClusterID string, consumerInfo clustercache.ClusterInfo) (*openfgav1.CheckRequest, error) {
+	user, contextualTuples, err := c.bindSubject(ctx, req, bind, consumerClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := c.accountMapping(apiBindings, "create", consumerClusterID, consumerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return accountCheck(bind, rendered, user, contextualTuples), nil
+}
+
+// checkPermissionClaims checks that the user binding the APIExport exportName
+// of the provider cluster may grant each of its permission claims, that is
+// holds the collection relation of every claimed verb and resource on the
+// consumer account. bind is the passed check of the APIExport.
+func (c *contextualAuthorizer) checkPermissionClaims(ctx context.Context, req authorization.Request, bind *openfgav1.CheckRequest, providerClusterName, exportName, consumerClusterID string, consumerInfo clustercache.ClusterInfo) authorization.Response {
+	if c.providers == nil {
+		klog.V(2).InfoS("provider clusters are not accessible, cannot verify permission claims", "clusterName", providerClusterName, "export", exportName)
+		return authorization.NoOpinion()
+	}
+
+	provider, err := c.providers.GetCluster(ctx, multicluster.ClusterName(providerClusterName))
+	if err != nil {
+		klog.ErrorS(err, "failed to get provider cluster, cannot verify permission claims", "clusterName", providerClusterName, "export", exportName)
+		return authorization.NoOpinion()
+	}
+
+	claims, err := readPermissionClaims(ctx, provider.GetClient(), exportName)
+	switch {
+	case apierrors.IsNotFound(err):
+		// a binding to a missing export grants nothing until the export
+		// exists and its claims are accepted in the binding
+		klog.V(2).InfoS("APIExport not found, no permission claims to verify", "clusterName", providerClusterName, "export", exportName)
+		return authorization.Allowed()
+	case apierrors.IsForbidden(err):
+		klog.ErrorS(err, "not allowed to read APIExport, cannot verify permission claims", "clusterName", providerClusterName, "export", exportName)
+		return authorization.NoOpinion()
+	case err != nil:
+		klog.ErrorS(err, "failed to read permission claims of APIExport", "clusterName", providerClusterName, "export", exportName)
+		return authorization.NoOpinion()
+	}
+	if len(claims) == 0 {
+		return authorization.Allowed()
+	}
+
+	user, contextualTuples, err := c.bindSubject(ctx, req, bind, consumerClusterID)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to map request subject, skipping", "user", req.Spec.User)
+		return authorization.NoOpinion()
+	}
+
+	var checks []claimCheck
+	for _, claim := range claims {
+		verbs := claim.Verbs
+		if slices.Contains(verbs, "*") {
+			verbs = claimAllVerbs
+		}
+
+		for _, verb := range verbs {
+			rendered, err := c.accountMapping(schema.GroupResource{Group: claim.Group, Resource: claim.Resource}, verb, consumerClusterID, consumerInfo)
+			if err != nil {
+				klog.ErrorS(err, "failed to map permission claim to OpenFGA", "claim", claim.String(), "verb", verb)
+				return authorization.NoOpinion()
+			}
+			checks = append(checks, claimCheck{claim: claim, verb: verb, check: accountCheck(bind, rendered, user, contextualTuples)})
+		}
+	}
+
+	rejected, err := c.firstRejected(ctx, checks)
+	if err != nil {
+		klog.ErrorS(err, "failed to perform OpenFGA check for permission claims")
+		return authorization.NoOpinion()
+	}
+	if rejected != nil {
+		return c.rejectClaim(req.Spec.User, rejected.claim, rejected.verb)
+	}
+
+	klog.V(5).InfoS("user may grant all permission claims", "user", req.Spec.User, "export", exportName)
+	return authorization.Allowed()
+}
+
+// claimCheck is the check whether a user may grant verb of claim.
+type claimCheck struct {
+	claim permissionClaim
+	verb  string
+	check *openfgav1.CheckRequest
+}
+
+// maxBatchCheckItems is the number of checks OpenFGA accepts in one BatchCheck
+// by default.
+const maxBatchCheckItems = 50
+
+// firstRejected performs checks, which share store, model, consistency and
+// context, in batches and returns the first one not allowed. Relations missing
+// from the authorization model are not allowed.
+func (c *contextualAuthorizer) firstRejected(ctx context.Context, checks []claimCheck) (*claimCheck, error) {
+	allowed := make([]bool, len(checks))
+	var batch []*openfgav1.BatchCheckItem
+	for i, cc := range checks {
+		mapped, err := c.checker.IsMapped(ctx, cc.check)
+		if err != nil {
+			return nil, err
+		}
+		if !mapped {
+			klog.V(2).InfoS("relation not defined in authorization model", "object", cc.check.TupleKey.Object, "relation", cc.check.TupleKey.Relation, "modelID", cc.check.AuthorizationModelId)
+			metrics.RecordUnmapped(handlerName)
+			continue
+		}
+		batch = append(batch, &openfgav1.BatchCheckItem{
+			TupleKey:         cc.check.TupleKey,
+			ContextualTuples: cc.check.ContextualTuples,
+			Context:          cc.check.Context,
+			CorrelationId:    strconv.Itoa(i),
+		})
+	}
+
+	for items := range slices.Chunk(batch, maxBatchCheckItems) {
+		first := checks[0].check
+		res, err := c.fga.BatchCheck(ctx, &openfgav1.BatchCheckRequest{
+			StoreId:              first.StoreId,
+			AuthorizationModelId: first.AuthorizationModelId,
+			Consistency:          first.Consistency,
+			Checks:               items,
+		})
+		if err != nil {
+			metrics.RecordCheck(handlerName, false, err)
+			return nil, err
+		}
+		for _, item := range items {
+			result, ok := res.GetResult()[item.CorrelationId]
+			if !ok {
+				return nil, fmt.Errorf("missing result of batch check %s", item.CorrelationId)
+			}
+			if checkErr := result.GetError(); checkErr != nil {
+				err := fmt.Errorf("batch check %s failed: %s", item.CorrelationId, checkErr.GetMessage())
+				metrics.RecordCheck(handlerName, false, err)
+				return nil, err
+			}
+			metrics.RecordCheck(handlerName, result.GetAllowed(), nil)
+			i, _ := strconv.Atoi(item.CorrelationId)
+			allowed[i] = result.GetAllowed()
+		}
+	}
+
+	for i := range checks {
+		if !allowed[i] {
+			return &checks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// bindSubject maps the user of a bind request in the consumer cluster, and
+// its groups if the model of bind defines group members.
+func (c *contextualAuthorizer) bindSubject(ctx context.Context, req authorization.Request, bind *openfgav1.CheckRequest, consumerClusterID string) (string, []*openfgav1.TupleKey, error) {
+	user, contextualTuples, err := c.checker.Subject(ctx, c.identities, req.Spec, consumerClusterID, bind.StoreId, bind.AuthorizationModelId)
+	if err != nil {
+		return "", nil, err
+	}
+	groupTuples, err := c.checker.GroupTuples(ctx, bind.StoreId, bind.AuthorizationModelId, user, req.Spec.Groups, c.groups, len(contextualTuples))
+	if err != nil {
+		return "", nil, err
+	}
+	return user, append(contextualTuples, groupTuples...), nil
+}
+
+// accountMapping renders the collection relation of verb on resource in the
+// consumer account.
+func (c *contextualAuthorizer) accountMapping(resource schema.GroupResource, verb, consumerClusterID string, consumerInfo clustercache.ClusterInfo) (mapping.Mapping, error) {
+	singular := resource.Resource
+	if consumerInfo.RESTMapper != nil {
+		// claimed resources may not be served by the consumer before the
+		// binding exists
+		if s, err := consumerInfo.RESTMapper.ResourceSingularizer(resource.Resource); err == nil {
+			singular = s
+		}
+	}
+
+	gvr := schema.GroupVersionResource{Group: resource.Group, Resource: resource.Resource}
+	group, objectType := c.objectType(consumerInfo, gvr, singular)
+
+	return c.mappings.Map(mapping.Attributes{
+		Verb:            verb,
+		APIGroup:        resource.Group,
+		Group:           group,
+		Resource:        resource.Resource,
+		Singular:        singular,
+		ObjectType:      objectType,
+		ClusterName:     consumerClusterID,
+		AccountName:     consumerInfo.AccountName,
+		ParentClusterID: consumerInfo.ParentClusterID,
+	})
+}
+
+// accountCheck returns the check of the collection relation rendered on the
+// consumer account for user, sent with the store, model and context of bind.
+func accountCheck(bind *openfgav1.CheckRequest, rendered mapping.Mapping, user string, contextualTuples []*openfgav1.TupleKey) *openfgav1.CheckRequest {
+	check := &openfgav1.CheckRequest{
+		StoreId:              bind.StoreId,
+		AuthorizationModelId: bind.AuthorizationModelId,
+		Consistency:          bind.Consistency,
+		Context:              bind.Context,
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			Object:   rendered.Account,
+			Relation: rendered.CollectionRelation,
+			User:     user,
+		},
+	}
+	if len(contextualTuples) > 0 {
+		check.ContextualTuples = &openfgav1.ContextualTupleKeys{TupleKeys: contextualTuples}
+	}
+	return check
+}
+
+// allowed performs check. Relations missing from the authorization model are
+// not allowed.
+func (c *contextualAuthorizer) allowed(ctx context.Context, check *openfgav1.CheckRequest) (bool, error) {
+	mapped, err := c.checker.IsMapped(ctx, check)
+	if err != nil {
+		return false, err
+	}
+	if !mapped {
+		klog.V(2).InfoS("relation not defined in authorization model", "object", check.TupleKey.Object, "relation", check.TupleKey.Relation, "modelID", check.AuthorizationModelId)
+		metrics.RecordUnmapped(handlerName)
+		return false, nil
+	}
+
+	response, err := c.fga.Check(ctx, check)
+	metrics.RecordCheck(handlerName, response.GetAllowed(), err)
+	if err != nil {
+		return false, err
+	}
+	return response.Allowed, nil
+}
+
+func (c *contextualAuthorizer) rejectClaim(user string, claim permissionClaim, verb string) authorization.Response {
+	klog.InfoS("user may not grant permission claim", "user", user, "claim", claim.String(), "verb", verb, "policy", c.permissionClaims)
+	if c.permissionClaims == PermissionClaimsDeny {
+		return authorization.DeniedWithReason(fmt.Sprintf("user %q may not grant permission claim %s on %s", user, verb, claim))
+	}
+	return authorization.NoOpinion()
+}
+
+// readPermissionClaims returns the permission claims of the APIExport name.
+func readPermissionClaims(ctx context.Context, cl client.Reader, name string) ([]permissionClaim, error) {
+	export := unstructured.Unstructured{}
+	export.SetGroupVersionKind(apiExportGVK)
+	if err := cl.Get(ctx, types.NamespacedName{Name: name}, &export); err != nil {
+		return nil, err
+	}
+
+	raw, _, err := unstructured.NestedSlice(export.Object, "spec", "permissionClaims")
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make([]permissionClaim, 0, len(raw))
+	for _, item := range raw {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid permission claim %v", item)
+		}
+		claim := permissionClaim{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &claim); err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+	return claims, nil
+}
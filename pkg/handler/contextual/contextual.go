@@ -9,41 +9,238 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/retry"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 )
 
 const (
-	maxRelationLength   = 50
+	handlerName         = "contextual"
+	maxRelationLength   = util.MaxRelationLength
 	systemClusterPrefix = "system:cluster:"
 	bindVerb            = "bind"
 )
 
 type contextualAuthorizer struct {
-	clusterKey          string
 	fga                 openfgav1.OpenFGAServiceClient
 	clusterCache        clustercache.Provider
 	cacheMissTracker    retry.Tracker[string]
 	cacheMissRetryAfter time.Duration
+
+	// subresourcesInheritingVerb lists the subresources which are checked
+	// with the plain verb of their parent resource.
+	subresourcesInheritingVerb map[string]bool
+	// verbAliases maps verbs to the verb whose relation they are checked with.
+	verbAliases       map[string]string
+	unknownVerbPolicy UnknownVerbPolicy
+
+	// ownerMaxDepth bounds how many levels of owner references are followed.
+	// Owner references are not followed if zero.
+	ownerMaxDepth int
+	ownerKinds    map[schema.GroupKind]bool
+
+	// typeNaming shortens groups exceeding the relation length limit.
+	typeNaming util.TypeNaming
+	// mappings render the objects, relations and contextual tuples of requests.
+	mappings *mapping.Mapper
+
+	models            openfga.ModelProvider
+	validateRelations bool
+	consistency       openfga.ConsistencyPolicy
+	// denyRelationFormat derives the deny relation checked before a
+	// relation, e.g. deny_%s. Deny relations are not checked if empty.
+	denyRelationFormat string
+	// checkContext builds the context OpenFGA conditions are evaluated against.
+	checkContext *openfga.ContextBuilder
+
+	identities *identity.Mapper
+	// groups selects the request groups sent as contextual group memberships.
+	groups identity.GroupFilter
+
+	// permissionClaims configures whether bind requests check that the user
+	// may grant the permission claims of the APIExport.
+	permissionClaims PermissionClaimPolicy
+	// providers gives access to the provider clusters the APIExports of
+	// permission claims are read from.
+	providers mcmanager.Manager
+	// bindUser configures how the check of the binding user is combined with
+	// the check of the APIExport on bind requests.
+	bindUser BindUserPolicy
+
+	// checker resolves models, validates relations and checks deny
+	// relations as configured by the options above. It is built in New.
+	checker *openfga.Checker
 }
 
 var _ authorization.Handler = &contextualAuthorizer{}
 
-func New(fga openfgav1.OpenFGAServiceClient, clusterCache clustercache.Provider, clusterKey string, cacheMissTracker retry.Tracker[string], cacheMissRetryAfter time.Duration) authorization.Handler {
-	return &contextualAuthorizer{
-		fga:                 fga,
-		clusterKey:          clusterKey,
-		clusterCache:        clusterCache,
-		cacheMissTracker:    cacheMissTracker,
-		cacheMissRetryAfter: cacheMissRetryAfter,
+// Option configures optional behavior of the contextual authorizer.
+type Option func(*contextualAuthorizer)
+
+// WithSubresourcesInheritingVerb configures subresources that are checked
+// with the verb of their parent resource instead of a subresource specific
+// relation like create_exec or update_status.
+func WithSubresourcesInheritingVerb(subresources ...string) Option {
+	return func(c *contextualAuthorizer) {
+		for _, subresource := range subresources {
+			c.subresourcesInheritingVerb[subresource] = true
+		}
+	}
+}
+
+// WithVerbAliases checks verbs with the relation of another verb, e.g.
+// patch with update.
+func WithVerbAliases(aliases map[string]string) Option {
+	return func(c *contextualAuthorizer) {
+		for verb, alias := range aliases {
+			c.verbAliases[verb] = alias
+		}
+	}
+}
+
+// WithUnknownVerbPolicy configures how verbs without an entry in the verb
+// table are checked. It defaults to UnknownVerbObject.
+func WithUnknownVerbPolicy(policy UnknownVerbPolicy) Option {
+	return func(c *contextualAuthorizer) {
+		c.unknownVerbPolicy = policy
+	}
+}
+
+// WithOwnerReferences parents named namespaced objects to their owners of
+// the given kinds, following owner references up to maxDepth levels, so that
+// access to e.g. a Deployment can grant access to its ReplicaSets and Pods.
+func WithOwnerReferences(maxDepth int, kinds ...schema.GroupKind) Option {
+	return func(c *contextualAuthorizer) {
+		c.ownerMaxDepth = maxDepth
+		for _, kind := range kinds {
+			c.ownerKinds[kind] = true
+		}
+	}
+}
+
+// WithTypeNaming configures how groups exceeding the relation length limit
+// are shortened in relations and object types.
+func WithTypeNaming(naming util.TypeNaming) Option {
+	return func(c *contextualAuthorizer) {
+		c.typeNaming = naming
+	}
+}
+
+// WithMappings configures the templates mapping requests to OpenFGA objects,
+// relations and contextual tuples.
+func WithMappings(mappings *mapping.Mapper) Option {
+	return func(c *contextualAuthorizer) {
+		c.mappings = mappings
+	}
+}
+
+// WithAuthorizationModels configures the provider resolving the authorization
+// model ID sent along with every check.
+func WithAuthorizationModels(models openfga.ModelProvider) Option {
+	return func(c *contextualAuthorizer) {
+		c.models = models
+	}
+}
+
+// WithRelationValidation skips checks whose object type or relation is not
+// defined in the store's authorization model. It requires WithAuthorizationModels.
+func WithRelationValidation(enabled bool) Option {
+	return func(c *contextualAuthorizer) {
+		c.validateRelations = enabled
+	}
+}
+
+// WithConsistency configures the consistency preference of checks per verb.
+func WithConsistency(consistency openfga.ConsistencyPolicy) Option {
+	return func(c *contextualAuthorizer) {
+		c.consistency = consistency
+	}
+}
+
+// WithDenyRelation checks the deny relation derived from format, e.g.
+// deny_%s, before every check and denies the request if it is satisfied.
+// Without WithRelationValidation, this is an additional check for every
+// request, even if the model does not define the deny relation.
+func WithDenyRelation(format string) Option {
+	return func(c *contextualAuthorizer) {
+		c.denyRelationFormat = format
 	}
 }
 
+// WithCheckContext passes the request attributes built by builder as
+// context to OpenFGA conditions.
+func WithCheckContext(builder *openfga.ContextBuilder) Option {
+	return func(c *contextualAuthorizer) {
+		c.checkContext = builder
+	}
+}
+
+// WithIdentityMapper configures how request subjects are mapped to OpenFGA users.
+func WithIdentityMapper(identities *identity.Mapper) Option {
+	return func(c *contextualAuthorizer) {
+		c.identities = identities
+	}
+}
+
+// WithGroups sends the request groups selected by filter as contextual
+// group:<name>#member tuples of the checked user.
+func WithGroups(filter identity.GroupFilter) Option {
+	return func(c *contextualAuthorizer) {
+		c.groups = filter
+	}
+}
+
+// WithPermissionClaims checks on bind requests that the binding user may
+// grant every permission claim of the APIExport, answering with policy if not.
+// APIExports are read from their provider cluster through providers.
+func WithPermissionClaims(policy PermissionClaimPolicy, providers mcmanager.Manager) Option {
+	return func(c *contextualAuthorizer) {
+		c.permissionClaims = policy
+		c.providers = providers
+	}
+}
+
+// WithBindUser additionally checks on bind requests that the binding user may
+// create APIBindings in the consumer account, combined with the check of the
+// APIExport according to policy.
+func WithBindUser(policy BindUserPolicy) Option {
+	return func(c *contextualAuthorizer) {
+		c.bindUser = policy
+	}
+}
+
+func New(fga openfgav1.OpenFGAServiceClient, clusterCache clustercache.Provider, cacheMissTracker retry.Tracker[string], cacheMissRetryAfter time.Duration, opts ...Option) authorization.Handler {
+	c := &contextualAuthorizer{
+		fga:                        fga,
+		clusterCache:               clusterCache,
+		cacheMissTracker:           cacheMissTracker,
+		cacheMissRetryAfter:        cacheMissRetryAfter,
+		subresourcesInheritingVerb: map[string]bool{},
+		verbAliases:                map[string]string{},
+		unknownVerbPolicy:          UnknownVerbObject,
+		ownerKinds:                 map[schema.GroupKind]bool{},
+		typeNaming:                 util.TypeNamingTruncate,
+		mappings:                   mapping.Default(),
+		identities:                 identity.DefaultMapper(),
+		permissionClaims:           PermissionClaimsIgnore,
+		bindUser:                   BindUserIgnore,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.checker = openfga.NewChecker(handlerName, c.fga, c.models, c.validateRelations, c.denyRelationFormat)
+	return c
+}
+
 func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
 	klog.V(5).Info("handling request in ContextualAuthorizer")
 
@@ -54,14 +251,12 @@ func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Req
 
 	attrs := req.Spec.ResourceAttributes
 
-	cn, ok := req.Spec.Extra[c.clusterKey]
-	if !ok || len(cn) == 0 {
-		klog.V(5).Infof("request does not contain expected Extra attribute %q, skipping", c.clusterKey)
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" && req.Attributes.ClusterPath == "" {
+		klog.V(5).Info("request does not contain a known cluster, skipping")
 		return authorization.NoOpinion()
 	}
 
-	clusterName := cn[0]
-
 	klog.V(5).InfoS("found cluster name", "clusterName", clusterName)
 
 	if req.Spec.ResourceAttributes == nil {
@@ -69,22 +264,26 @@ func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Req
 		return authorization.NoOpinion()
 	}
 
+	if clusterName == "" {
+		// workspace paths are resolved once their cluster is engaged
+		return c.cacheMiss(req.Attributes.ClusterPath)
+	}
+
 	// Handle bind verb for kcp separately
 	// it requires consumer and provider cluster info
 	if attrs.Verb == bindVerb && attrs.Group == "apis.kcp.io" {
 		return c.handleKCPBindCheck(ctx, req)
 	}
 
+	verb, scope := c.resolveVerb(attrs.Verb)
+	if scope == scopeSkip {
+		klog.V(5).InfoS("verb is not handled by contextual authorizer, skipping", "verb", attrs.Verb)
+		return authorization.NoOpinion()
+	}
+
 	clusterInfo, ok := c.clusterCache.Get(multicluster.ClusterName(clusterName))
 	if !ok {
-		if c.cacheMissTracker.ShouldRetry(clusterName) {
-			klog.V(5).InfoS("cluster not found in cache, retrying", "clusterName", clusterName)
-			c.cacheMissTracker.Retried(clusterName)
-			return authorization.Retry(c.cacheMissRetryAfter)
-		}
-
-		klog.V(5).InfoS("cluster not found in cache", "clusterName", clusterName)
-		return authorization.NoOpinion()
+		return c.cacheMiss(clusterName)
 	}
 
 	klog.V(5).InfoS("found cluster info in cache",
@@ -125,28 +324,63 @@ func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Req
 		return authorization.NoOpinion()
 	}
 
-	group, objectType := buildObjectType(gvr, singular)
+	group, objectType := c.objectType(clusterInfo, gvr, singular)
+
+	rendered, err := c.mappings.Map(mapping.Attributes{
+		Verb:            verb,
+		APIGroup:        attrs.Group,
+		Group:           group,
+		Version:         version,
+		Resource:        attrs.Resource,
+		Subresource:     attrs.Subresource,
+		Singular:        singular,
+		ObjectType:      objectType,
+		Namespace:       attrs.Namespace,
+		Name:            attrs.Name,
+		ClusterName:     clusterName,
+		AccountName:     clusterInfo.AccountName,
+		ParentClusterID: clusterInfo.ParentClusterID,
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to map request to OpenFGA", "GVR", gvr)
+		return authorization.NoOpinion()
+	}
+
+	object := rendered.Object
+	relation := rendered.Relation
 
-	object := fmt.Sprintf("%s:%s/%s", objectType, clusterName, attrs.Name)
-	relation := attrs.Verb
+	hasParent := scope == scopeParent
 
-	hasParent := util.ResolveOnParent(attrs.Verb)
+	// Subresources like pods/exec or deployments/scale are always checked on
+	// the named parent object, with the subresource folded into the relation.
+	if attrs.Subresource != "" && !c.subresourcesInheritingVerb[attrs.Subresource] {
+		relation = rendered.SubresourceRelation
+		hasParent = false
+	}
 
-	accountObject := fmt.Sprintf("core_platform-mesh_io_account:%s/%s", clusterInfo.ParentClusterID, clusterInfo.AccountName)
+	accountObject := rendered.Account
 
 	if hasParent {
-		relation = fmt.Sprintf("%s_%s_%s", relation, group, gvr.Resource)
+		relation = rendered.CollectionRelation
 		object = accountObject
 	}
 
 	var contextualTuples []*openfgav1.TupleKey
-	if isNamespaced {
-		namespaceObject := fmt.Sprintf("core_namespace:%s/%s", clusterName, attrs.Namespace)
+	switch {
+	case isNamespaced && attrs.Namespace == "" && hasParent:
+		// Requests across all namespaces, like listing the pods of every
+		// namespace, are checked on the account without a namespace.
+		klog.V(5).InfoS("request spans all namespaces, checking on account", "verb", attrs.Verb, "resource", attrs.Resource)
+	case isNamespaced && attrs.Namespace == "":
+		klog.V(5).InfoS("request for namespaced object does not contain a namespace, skipping", "verb", attrs.Verb, "resource", attrs.Resource, "name", attrs.Name)
+		return authorization.NoOpinion()
+	case isNamespaced:
+		namespaceObject := rendered.Namespace
 
 		// parent the namespace to the account
 		contextualTuples = append(contextualTuples, &openfgav1.TupleKey{
 			Object:   namespaceObject,
-			Relation: "parent",
+			Relation: rendered.ParentRelation,
 			User:     accountObject,
 		})
 
@@ -156,26 +390,53 @@ func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Req
 			// parent the object to the namespace
 			contextualTuples = append(contextualTuples, &openfgav1.TupleKey{
 				Object:   object,
-				Relation: "parent",
+				Relation: rendered.ParentRelation,
 				User:     namespaceObject,
 			})
+
+			if c.ownerMaxDepth > 0 && ownerVerbs[verb] && attrs.Name != "" {
+				contextualTuples = append(contextualTuples, c.ownerTuples(ctx, clusterInfo, clusterName, gvk, attrs.Namespace, attrs.Name, object, namespaceObject, rendered.ParentRelation)...)
+			}
 		}
-	} else {
+	case attrs.Name != "":
 		contextualTuples = append(contextualTuples, &openfgav1.TupleKey{
-			Object:   fmt.Sprintf("%s:%s/%s", objectType, clusterName, attrs.Name),
-			Relation: "parent",
+			Object:   rendered.Object,
+			Relation: rendered.ParentRelation,
 			User:     accountObject,
 		})
 	}
+	contextualTuples = append(contextualTuples, rendered.Tuples...)
+
+	modelID, err := c.checker.ModelID(ctx, clusterInfo.StoreID, clusterInfo.AuthorizationModelID)
+	if err != nil {
+		klog.ErrorS(err, "failed to resolve authorization model", "storeID", clusterInfo.StoreID)
+		return authorization.NoOpinion()
+	}
+
+	user, subjectTuples, err := c.checker.Subject(ctx, c.identities, req.Spec, clusterName, clusterInfo.StoreID, modelID)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to map request subject, skipping", "user", req.Spec.User)
+		return authorization.NoOpinion()
+	}
+	contextualTuples = append(contextualTuples, subjectTuples...)
+
+	groupTuples, err := c.checker.GroupTuples(ctx, clusterInfo.StoreID, modelID, user, req.Spec.Groups, c.groups, len(contextualTuples))
+	if err != nil {
+		klog.ErrorS(err, "failed to load authorization model", "storeID", clusterInfo.StoreID, "modelID", modelID)
+		return authorization.NoOpinion()
+	}
+	contextualTuples = append(contextualTuples, groupTuples...)
 
-	klog.InfoS("calling fga", "object", object, "relation", relation)
+	klog.InfoS("calling fga", "object", object, "relation", relation, "subresource", attrs.Subresource, "modelID", modelID)
 
 	check := &openfgav1.CheckRequest{
-		StoreId: clusterInfo.StoreID,
+		StoreId:              clusterInfo.StoreID,
+		AuthorizationModelId: modelID,
+		Consistency:          c.consistency.For(attrs.Verb),
 		TupleKey: &openfgav1.CheckRequestTupleKey{
 			Object:   object,
 			Relation: relation,
-			User:     fmt.Sprintf("user:%s", req.Spec.User),
+			User:     user,
 		},
 	}
 
@@ -185,7 +446,29 @@ func (c *contextualAuthorizer) Handle(ctx context.Context, req authorization.Req
 		}
 	}
 
+	check.Context, err = c.checkContext.Build(req.Spec, clusterName, clusterInfo.AccountName)
+	if err != nil {
+		klog.ErrorS(err, "failed to build check context")
+		return authorization.NoOpinion()
+	}
+
+	if res, ok := c.checker.Denied(ctx, check); ok {
+		return res
+	}
+
+	mapped, err := c.checker.IsMapped(ctx, check)
+	if err != nil {
+		klog.ErrorS(err, "failed to load authorization model", "storeID", check.StoreId, "modelID", modelID)
+		return authorization.NoOpinion()
+	}
+	if !mapped {
+		klog.V(2).InfoS("relation not defined in authorization model, skipping check", "object", object, "relation", relation, "modelID", modelID)
+		metrics.RecordUnmapped(handlerName)
+		return authorization.NoOpinion()
+	}
+
 	response, err := c.fga.Check(ctx, check)
+	metrics.RecordCheck(handlerName, response.GetAllowed(), err)
 	if err != nil {
 		klog.ErrorS(err, "failed to perform OpenFGA check")
 		return authorization.NoOpinion()
@@ -207,12 +490,11 @@ func (c *contextualAuthorizer) handleKCPBindCheck(ctx context.Context, req autho
 		return authorization.NoOpinion()
 	}
 
-	providerCluster, ok := req.Spec.Extra[c.clusterKey]
-	if !ok || len(providerCluster) == 0 {
-		klog.V(5).InfoS("bind request missing provider cluster in Extra", "clusterKey", c.clusterKey)
+	providerClusterName := req.Attributes.ClusterName
+	if providerClusterName == "" {
+		klog.V(5).Info("bind request missing provider cluster")
 		return authorization.NoOpinion()
 	}
-	providerClusterName := providerCluster[0]
 
 	consumerClusterID := ""
 	for _, group := range req.Spec.Groups {
@@ -229,13 +511,7 @@ func (c *contextualAuthorizer) handleKCPBindCheck(ctx context.Context, req autho
 
 	consumerInfo, ok := c.clusterCache.Get(multicluster.ClusterName(consumerClusterID))
 	if !ok {
-		if c.cacheMissTracker.ShouldRetry(consumerClusterID) {
-			klog.V(5).InfoS("consumer cluster not found in cache, retrying", "clusterID", consumerClusterID)
-			c.cacheMissTracker.Retried(consumerClusterID)
-			return authorization.Retry(c.cacheMissRetryAfter)
-		}
-		klog.V(5).InfoS("consumer cluster not found in cache", "clusterID", consumerClusterID)
-		return authorization.NoOpinion()
+		return c.cacheMiss(consumerClusterID)
 	}
 
 	klog.V(5).InfoS("fetched consumer cluster info from cache",
@@ -254,15 +530,26 @@ func (c *contextualAuthorizer) handleKCPBindCheck(ctx context.Context, req autho
 		Resource: attrs.Resource,
 	}
 
-	_, resourceObjectType := buildObjectType(gvr, singular)
+	_, resourceObjectType := c.objectType(consumerInfo, gvr, singular)
 
 	resourceToBind := fmt.Sprintf("%s:%s/%s", resourceObjectType, providerClusterName, attrs.Name)
-	consumerAccountObject := fmt.Sprintf("core_platform-mesh_io_account:%s/%s",
-		consumerInfo.ParentClusterID,
-		consumerInfo.AccountName)
+	rendered, err := c.accountMapping(gvr.GroupResource(), bindVerb, consumerClusterID, consumerInfo)
+	if err != nil {
+		klog.ErrorS(err, "failed to map consumer account to OpenFGA", "resource", attrs.Resource)
+		return authorization.NoOpinion()
+	}
+	consumerAccountObject := rendered.Account
+
+	modelID, err := c.checker.ModelID(ctx, consumerInfo.StoreID, consumerInfo.AuthorizationModelID)
+	if err != nil {
+		klog.ErrorS(err, "failed to resolve authorization model", "storeID", consumerInfo.StoreID)
+		return authorization.NoOpinion()
+	}
 
 	check := &openfgav1.CheckRequest{
-		StoreId: consumerInfo.StoreID,
+		StoreId:              consumerInfo.StoreID,
+		AuthorizationModelId: modelID,
+		Consistency:          c.consistency.For(bindVerb),
 		TupleKey: &openfgav1.CheckRequestTupleKey{
 			Object:   consumerAccountObject,
 			Relation: bindVerb,
@@ -271,30 +558,78 @@ func (c *contextualAuthorizer) handleKCPBindCheck(ctx context.Context, req autho
 	}
 	klog.InfoS("calling fga", "object", consumerAccountObject, "relation", attrs.Verb)
 
-	response, err := c.fga.Check(ctx, check)
+	check.Context, err = c.checkContext.Build(req.Spec, consumerClusterID, consumerInfo.AccountName)
+	if err != nil {
+		klog.ErrorS(err, "failed to build check context")
+		return authorization.NoOpinion()
+	}
+
+	if res, ok := c.checker.Denied(ctx, check); ok {
+		return res
+	}
+
+	exportAllowed, err := c.allowed(ctx, check)
 	if err != nil {
 		klog.ErrorS(err, "failed to perform OpenFGA check for bind")
 		return authorization.NoOpinion()
 	}
 
-	klog.InfoS("performed OpenFGA bind check", "allowed", response.Allowed)
+	klog.InfoS("performed OpenFGA bind check", "allowed", exportAllowed)
 
-	if response.Allowed {
+	if !exportAllowed && c.bindUser != BindUserOr {
+		return authorization.NoOpinion()
+	}
+	if c.bindUser == BindUserIgnore && c.permissionClaims == PermissionClaimsIgnore {
 		return authorization.Allowed()
 	}
 
-	return authorization.NoOpinion()
-}
+	if c.bindUser == BindUserAnd || (c.bindUser == BindUserOr && !exportAllowed) {
+		userCheck, err := c.bindUserCheck(ctx, req, check, consumerClusterID, consumerInfo)
+		if err != nil {
+			klog.ErrorS(err, "failed to map bind user check", "user", req.Spec.User)
+			return authorization.NoOpinion()
+		}
+
+		if res, ok := c.checker.Denied(ctx, userCheck); ok {
+			return res
+		}
 
-func buildObjectType(gvr schema.GroupVersionResource, singular string) (string, string) {
-	group := util.CapGroupToRelationLength(gvr, maxRelationLength)
-	group = strings.ReplaceAll(group, ".", "_")
+		userAllowed, err := c.allowed(ctx, userCheck)
+		if err != nil {
+			klog.ErrorS(err, "failed to perform OpenFGA check for bind user")
+			return authorization.NoOpinion()
+		}
+
+		klog.InfoS("performed OpenFGA bind user check", "user", userCheck.TupleKey.User, "allowed", userAllowed)
 
-	objectType := fmt.Sprintf("%s_%s", group, singular)
-	longestObjectType := fmt.Sprintf("create_%ss", objectType)
-	if len(longestObjectType) > maxRelationLength {
-		objectType = objectType[len(longestObjectType)-maxRelationLength:]
+		if !userAllowed {
+			return authorization.NoOpinion()
+		}
 	}
 
-	return group, objectType
+	if c.permissionClaims == PermissionClaimsIgnore {
+		return authorization.Allowed()
+	}
+	return c.checkPermissionClaims(ctx, req, check, providerClusterName, attrs.Name, consumerClusterID, consumerInfo)
+}
+
+// cacheMiss answers requests for a cluster which is not ready in the cluster
+// cache.
+func (c *contextualAuthorizer) cacheMiss(clusterName string) authorization.Response {
+	return clustercache.CacheMiss(c.clusterCache, c.cacheMissTracker, c.cacheMissRetryAfter, clusterName)
+}
+
+// objectType returns the relation group and the OpenFGA object type of gvr,
+// and logs if another resource of the cluster was seen with the same type.
+func (c *contextualAuthorizer) objectType(info clustercache.ClusterInfo, gvr schema.GroupVersionResource, singular string) (string, string) {
+	if info.Types != nil {
+		if collision, ok := info.Types.Register(gvr, singular); ok {
+			klog.InfoS("Resources map to the same OpenFGA object type",
+				"path", info.Path,
+				"objectType", collision.ObjectType,
+				"resource", collision.Existing,
+				"collidingResource", collision.Colliding)
+		}
+	}
+	return c.typeNaming.ObjectType(gvr, singular, maxRelationLength)
 }
@@ -2,7 +2,10 @@ package contextual_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,17 +14,36 @@ import (
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/contextual"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	v1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 )
 
 func TestHandler(t *testing.T) {
+	consistency, err := openfga.ParseConsistencyPolicy(map[string]string{"create": "HIGHER_CONSISTENCY", "*": "MINIMIZE_LATENCY"})
+	assert.NoError(t, err)
+
+	groups, err := identity.NewGroupFilter([]string{"*"}, []string{"system:*"})
+	assert.NoError(t, err)
+
+	uids, err := identity.NewMapper(identity.MapperConfig{Source: identity.SourceUID})
+	assert.NoError(t, err)
+
 	testCases := []struct {
 		name                  string
 		req                   authorization.Request
@@ -29,6 +51,8 @@ func TestHandler(t *testing.T) {
 		fgaMocks              func(openfga *mocks.OpenFGAServiceClient)
 		clusterCacheMocks     func(cc *mocks.ClusterCacheProvider)
 		cacheMissTrackerMocks func(tracker *mocks.Tracker[string])
+		opts                  []contextual.Option
+		modelMocks            func(models *mocks.ModelProvider)
 	}{
 		{
 			name: "should skip processing if clusterKey extra attrs not present",
@@ -50,12 +74,34 @@ func TestHandler(t *testing.T) {
 			res: authorization.Retry(time.Second),
 			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
 				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateEngaging, nil)
 			},
 			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
 				tracker.EXPECT().ShouldRetry("a").Return(true)
 				tracker.EXPECT().Retried("a")
 			},
 		},
+		{
+			name: "should retry processing if the workspace path is not resolved yet",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"root:orgs:acme"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{},
+					},
+				},
+			},
+			res: authorization.Retry(time.Second),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().State(multicluster.ClusterName("root:orgs:acme")).Return(clustercache.StateUnknown, nil)
+			},
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
+				tracker.EXPECT().ShouldRetry("root:orgs:acme").Return(true)
+				tracker.EXPECT().Retried("root:orgs:acme")
+			},
+		},
 		{
 			name: "should skip processing if cluster not found in cache and cacheMissTracker returns false",
 			req: authorization.Request{
@@ -71,7 +117,48 @@ func TestHandler(t *testing.T) {
 			res: authorization.NoOpinion(),
 			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
 				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateUnknown, nil)
+			},
+		},
+		{
+			name: "should skip processing without retry if cluster is ignored by the cache",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{},
+					},
+				},
+			},
+			res: authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateIgnored, nil)
+			},
+			// the tracker must not be consulted
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {},
+		},
+		{
+			name: "should skip processing without retry if cluster failed to engage",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{},
+					},
+				},
+			},
+			res: authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateFailed, errors.New("storeId not found in Store status"))
 			},
+			// the tracker must not be consulted
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {},
 		},
 		{
 			name: "should skip processing if restmapper cannot resolve GVK",
@@ -105,6 +192,7 @@ func TestHandler(t *testing.T) {
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -171,6 +259,7 @@ func TestHandler(t *testing.T) {
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -246,6 +335,7 @@ func TestHandler(t *testing.T) {
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -313,6 +403,7 @@ func TestHandler(t *testing.T) {
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -460,6 +551,7 @@ func TestHandler(t *testing.T) {
 			res: authorization.NoOpinion(),
 			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
 				cc.EXPECT().Get(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.StateUnknown, nil)
 			},
 		},
 		{
@@ -535,6 +627,7 @@ func TestHandler(t *testing.T) {
 			res: authorization.Retry(time.Second),
 			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
 				cc.EXPECT().Get(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.StateEngaging, nil)
 			},
 			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
 				tracker.EXPECT().ShouldRetry("consumer-cluster-id").Return(true)
@@ -602,35 +695,2096 @@ func TestHandler(t *testing.T) {
 				)
 			},
 		},
-	}
-	for _, test := range testCases {
-		t.Run(test.name, func(t *testing.T) {
+		{
+			name: "should check subresource relation on the named object",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Version:     "v1",
+							Resource:    "pods",
+							Subresource: "exec",
+							Verb:        "create",
+							Name:        "web-0",
+							Namespace:   "test-ns",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
 
-			cc := mocks.NewClusterCacheProvider(t)
-			if test.clusterCacheMocks != nil {
-				test.clusterCacheMocks(cc)
-			}
+				gv := schema.GroupVersion{Version: "v1"}
 
-			openfga := mocks.NewOpenFGAServiceClient(t)
-			if test.fgaMocks != nil {
-				test.fgaMocks(openfga)
-			}
+				rm.AddSpecific(
+					gv.WithKind("Pod"),
+					gv.WithResource("pods"),
+					gv.WithResource("pod"),
+					meta.RESTScopeNamespace,
+				)
 
-			cacheMissTracker := mocks.NewTracker[string](t)
-			if test.cacheMissTrackerMocks != nil {
-				test.cacheMissTrackerMocks(cacheMissTracker)
-			} else {
-				// Default: ShouldRetry returns false so handler proceeds when cluster is in cache
-				cacheMissTracker.EXPECT().ShouldRetry(mock.Anything).Return(false).Maybe()
-			}
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
 
-			h := contextual.New(openfga, cc, "authorization.kubernetes.io/cluster-name", cacheMissTracker, time.Second)
+						tuples := in.ContextualTuples.TupleKeys
 
-			ctx := t.Context()
+						contains := slices.ContainsFunc(tuples, func(tk *openfgav1.TupleKey) bool {
+							return tk.User == "core_namespace:a/test-ns" &&
+								tk.Relation == "parent" &&
+								tk.Object == "core_pod:a/web-0"
+						})
+
+						assert.True(t, contains)
 
-			res := h.Handle(ctx, test.req)
+						assert.Equal(t, "core_pod:a/web-0", in.TupleKey.Object)
+						assert.Equal(t, "create_exec", in.TupleKey.Relation)
 
-			assert.Equal(t, test.res, res)
-		})
-	}
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check status subresource with a dedicated relation",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:       "test.platform-mesh.io",
+							Version:     "v1alpha1",
+							Resource:    "tests",
+							Subresource: "status",
+							Verb:        "update",
+							Name:        "test-sample",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "test_platform-mesh_io_test:a/test-sample", in.TupleKey.Object)
+						assert.Equal(t, "update_status", in.TupleKey.Relation)
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check subresource with parent verb if configured to inherit",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:       "test.platform-mesh.io",
+							Version:     "v1alpha1",
+							Resource:    "tests",
+							Subresource: "status",
+							Verb:        "get",
+							Name:        "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithSubresourcesInheritingVerb("status")},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "test_platform-mesh_io_test:a/test-sample", in.TupleKey.Object)
+						assert.Equal(t, "get", in.TupleKey.Relation)
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check with the consistency preference of the verb",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "create",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithConsistency(consistency)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "create_test_platform-mesh_io_tests" &&
+						in.Consistency == openfgav1.ConsistencyPreference_HIGHER_CONSISTENCY
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should send selected groups as contextual memberships",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User:   "alice",
+						Groups: []string{"admins", "system:authenticated"},
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithGroups(groups)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						tuples := in.ContextualTuples.TupleKeys
+
+						assert.True(t, slices.ContainsFunc(tuples, func(tk *openfgav1.TupleKey) bool {
+							return tk.User == "user:alice" && tk.Relation == "member" && tk.Object == "group:admins"
+						}))
+						assert.False(t, slices.ContainsFunc(tuples, func(tk *openfgav1.TupleKey) bool {
+							return tk.Object == "group:system:authenticated"
+						}))
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check service accounts with their own type",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "system:serviceaccount:test-ns:builder",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Version:   "v1",
+							Resource:  "configmaps",
+							Verb:      "get",
+							Name:      "settings",
+							Namespace: "test-ns",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{Version: "v1"}
+
+				rm.AddSpecific(
+					gv.WithKind("ConfigMap"),
+					gv.WithResource("configmaps"),
+					gv.WithResource("configmap"),
+					meta.RESTScopeNamespace,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "core_serviceaccount:a/test-ns/builder", in.TupleKey.User)
+
+						assert.True(t, slices.ContainsFunc(in.ContextualTuples.TupleKeys, func(tk *openfgav1.TupleKey) bool {
+							return tk.User == "core_namespace:a/test-ns" &&
+								tk.Relation == "parent" &&
+								tk.Object == "core_serviceaccount:a/test-ns/builder"
+						}))
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check with the user mapped by the identity mapper",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User:   "alice",
+						UID:    "1234",
+						Groups: []string{"admins"},
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithIdentityMapper(uids), contextual.WithGroups(groups)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "user:1234", in.TupleKey.User)
+
+						assert.True(t, slices.ContainsFunc(in.ContextualTuples.TupleKeys, func(tk *openfgav1.TupleKey) bool {
+							return tk.User == "user:1234" && tk.Object == "group:admins"
+						}))
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should skip processing if the request subject cannot be mapped",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.NoOpinion(),
+			opts: []contextual.Option{contextual.WithIdentityMapper(uids)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+		},
+		{
+			name: "should pass request attributes as check context",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+							"environment": {"prod"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithCheckContext(openfga.NewContextBuilder("environment"))},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						values := in.Context.AsMap()
+						assert.Equal(t, "get", values["verb"])
+						assert.Equal(t, "test-sample", values["name"])
+						assert.Equal(t, "a", values["cluster_name"])
+						assert.Equal(t, "origin-account", values["account_name"])
+						assert.Equal(t, map[string]any{"environment": []any{"prod"}}, values["extra"])
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check with the resolved authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "store-id", in.StoreId)
+						assert.Equal(t, "model-id", in.AuthorizationModelId)
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check with the authorization model pinned on the Store",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:              "store-id",
+					RESTMapper:           rm,
+					AccountName:          "origin-account",
+					ParentClusterID:      "origin",
+					AuthorizationModelID: "pinned-model-id",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "store-id", in.StoreId)
+						assert.Equal(t, "pinned-model-id", in.AuthorizationModelId)
+
+						return &openfgav1.CheckResponse{
+							Allowed: true,
+						}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should skip processing if authorization model cannot be resolved",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res: authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("", errors.New("unavailable"))
+			},
+		},
+		{
+			name: "should check relation defined in the authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []contextual.Option{contextual.WithRelationValidation(true)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "store-id", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{
+							Type:      "test_platform-mesh_io_test",
+							Relations: map[string]*openfgav1.Userset{"get": {}},
+						},
+					},
+				}), nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should skip check for relation not defined in the authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.NoOpinion(),
+			opts: []contextual.Option{contextual.WithRelationValidation(true)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "store-id", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{
+							Type:      "test_platform-mesh_io_test",
+							Relations: map[string]*openfgav1.Userset{"update": {}},
+						},
+					},
+				}), nil)
+			},
+		},
+		{
+			name: "should skip processing if the authorization model cannot be loaded",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "test.platform-mesh.io",
+							Version:  "v1alpha1",
+							Resource: "tests",
+							Verb:     "get",
+							Name:     "test-sample",
+						},
+					},
+				},
+			},
+			res:  authorization.NoOpinion(),
+			opts: []contextual.Option{contextual.WithRelationValidation(true)},
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+
+				gv := schema.GroupVersion{
+					Group:   "test.platform-mesh.io",
+					Version: "v1alpha1",
+				}
+
+				rm.AddSpecific(
+					gv.WithKind("Test"),
+					gv.WithResource("tests"),
+					gv.WithResource("test"),
+					meta.RESTScopeRoot,
+				)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "store-id", "model-id").Return(nil, errors.New("unavailable"))
+			},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			cc := mocks.NewClusterCacheProvider(t)
+			if test.clusterCacheMocks != nil {
+				test.clusterCacheMocks(cc)
+			}
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			if test.fgaMocks != nil {
+				test.fgaMocks(openfga)
+			}
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+			if test.cacheMissTrackerMocks != nil {
+				test.cacheMissTrackerMocks(cacheMissTracker)
+			} else {
+				// Default: ShouldRetry returns false so handler proceeds when cluster is in cache
+				cacheMissTracker.EXPECT().ShouldRetry(mock.Anything).Return(false).Maybe()
+			}
+
+			opts := test.opts
+			if test.modelMocks != nil {
+				models := mocks.NewModelProvider(t)
+				test.modelMocks(models)
+				opts = append(opts, contextual.WithAuthorizationModels(models))
+			}
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, opts...)
+
+			ctx := t.Context()
+
+			req, err := authorization.NewRequest(test.req.SubjectAccessReview, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(ctx, req)
+
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestHandler_NamespaceScope(t *testing.T) {
+	testCases := []struct {
+		name       string
+		resource   string
+		verb       string
+		namespace  string
+		objectName string
+		res        authorization.Response
+		object     string
+		relation   string
+		tuples     []string
+	}{
+		{
+			name:     "should check list across all namespaces on the account",
+			resource: "pods",
+			verb:     "list",
+			res:      authorization.Allowed(),
+			object:   "core_platform-mesh_io_account:origin/origin-account",
+			relation: "list_core_pods",
+		},
+		{
+			name:     "should check watch across all namespaces on the account",
+			resource: "pods",
+			verb:     "watch",
+			res:      authorization.Allowed(),
+			object:   "core_platform-mesh_io_account:origin/origin-account",
+			relation: "watch_core_pods",
+		},
+		{
+			name:     "should check deletecollection across all namespaces on the account",
+			resource: "pods",
+			verb:     "deletecollection",
+			res:      authorization.Allowed(),
+			object:   "core_platform-mesh_io_account:origin/origin-account",
+			relation: "deletecollection_core_pods",
+		},
+		{
+			name:      "should check list in a namespace on the namespace",
+			resource:  "pods",
+			verb:      "list",
+			namespace: "test-ns",
+			res:       authorization.Allowed(),
+			object:    "core_namespace:a/test-ns",
+			relation:  "list_core_pods",
+			tuples:    []string{"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account"},
+		},
+		{
+			name:      "should check deletecollection in a namespace on the namespace",
+			resource:  "pods",
+			verb:      "deletecollection",
+			namespace: "test-ns",
+			res:       authorization.Allowed(),
+			object:    "core_namespace:a/test-ns",
+			relation:  "deletecollection_core_pods",
+			tuples:    []string{"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account"},
+		},
+		{
+			name:     "should check deletecollection of cluster scoped resources on the account",
+			resource: "namespaces",
+			verb:     "deletecollection",
+			res:      authorization.Allowed(),
+			object:   "core_platform-mesh_io_account:origin/origin-account",
+			relation: "deletecollection_core_namespaces",
+		},
+		{
+			name:       "should skip named requests for namespaced objects without namespace",
+			resource:   "pods",
+			verb:       "get",
+			objectName: "web-0",
+			res:        authorization.NoOpinion(),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Version: "v1"}
+			rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+			rm.AddSpecific(gv.WithKind("Namespace"), gv.WithResource("namespaces"), gv.WithResource("namespace"), meta.RESTScopeRoot)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+				StoreID:         "store-id",
+				RESTMapper:      rm,
+				AccountName:     "origin-account",
+				ParentClusterID: "origin",
+			}, true)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			if test.object != "" {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, test.object, in.TupleKey.Object)
+						assert.Equal(t, test.relation, in.TupleKey.Relation)
+
+						var tuples []string
+						for _, tk := range in.GetContextualTuples().GetTupleKeys() {
+							tuples = append(tuples, fmt.Sprintf("%s#%s@%s", tk.Object, tk.Relation, tk.User))
+						}
+						assert.Equal(t, test.tuples, tuples)
+
+						return &openfgav1.CheckResponse{Allowed: true}, nil
+					},
+				)
+			}
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "alice",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Version:   "v1",
+						Resource:  test.resource,
+						Verb:      test.verb,
+						Namespace: test.namespace,
+						Name:      test.objectName,
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestHandler_Verbs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		verb       string
+		resource   string
+		objectName string
+		opts       []contextual.Option
+		res        authorization.Response
+		object     string
+		relation   string
+	}{
+		{
+			name:       "should check patch on the named object",
+			verb:       "patch",
+			objectName: "web-0",
+			res:        authorization.Allowed(),
+			object:     "core_pod:a/web-0",
+			relation:   "patch",
+		},
+		{
+			name:       "should check patch as update if aliased",
+			verb:       "patch",
+			objectName: "web-0",
+			opts:       []contextual.Option{contextual.WithVerbAliases(map[string]string{"patch": "update"})},
+			res:        authorization.Allowed(),
+			object:     "core_pod:a/web-0",
+			relation:   "update",
+		},
+		{
+			name:       "should check escalate on the named role",
+			verb:       "escalate",
+			resource:   "roles",
+			objectName: "admin",
+			res:        authorization.Allowed(),
+			object:     "rbac_authorization_k8s_io_role:a/admin",
+			relation:   "escalate",
+		},
+		{
+			name:     "should skip impersonate",
+			verb:     "impersonate",
+			resource: "users",
+			res:      authorization.NoOpinion(),
+		},
+		{
+			name:       "should check unknown verbs on the named object by default",
+			verb:       "proxy",
+			objectName: "web-0",
+			res:        authorization.Allowed(),
+			object:     "core_pod:a/web-0",
+			relation:   "proxy",
+		},
+		{
+			name:     "should check unknown verbs on the parent if configured",
+			verb:     "proxy",
+			opts:     []contextual.Option{contextual.WithUnknownVerbPolicy(contextual.UnknownVerbParent)},
+			res:      authorization.Allowed(),
+			object:   "core_namespace:a/test-ns",
+			relation: "proxy_core_pods",
+		},
+		{
+			name:       "should skip unknown verbs if configured",
+			verb:       "proxy",
+			objectName: "web-0",
+			opts:       []contextual.Option{contextual.WithUnknownVerbPolicy(contextual.UnknownVerbSkip)},
+			res:        authorization.NoOpinion(),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			resource, group := test.resource, ""
+			if resource == "" {
+				resource = "pods"
+			}
+			if resource == "roles" {
+				group = "rbac.authorization.k8s.io"
+			}
+
+			cc := mocks.NewClusterCacheProvider(t)
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			if test.object != "" {
+				rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+				core := schema.GroupVersion{Version: "v1"}
+				rbac := schema.GroupVersion{Group: "rbac.authorization.k8s.io", Version: "v1"}
+				rm.AddSpecific(core.WithKind("Pod"), core.WithResource("pods"), core.WithResource("pod"), meta.RESTScopeNamespace)
+				rm.AddSpecific(rbac.WithKind("Role"), rbac.WithResource("roles"), rbac.WithResource("role"), meta.RESTScopeNamespace)
+
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+					StoreID:         "store-id",
+					RESTMapper:      rm,
+					AccountName:     "origin-account",
+					ParentClusterID: "origin",
+				}, true)
+
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, test.object, in.TupleKey.Object)
+						assert.Equal(t, test.relation, in.TupleKey.Relation)
+
+						return &openfgav1.CheckResponse{Allowed: true}, nil
+					},
+				)
+			}
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, test.opts...)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "alice",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Group:     group,
+						Version:   "v1",
+						Resource:  resource,
+						Verb:      test.verb,
+						Namespace: "test-ns",
+						Name:      test.objectName,
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestHandler_DenyRelation(t *testing.T) {
+	isDeny := func(in *openfgav1.CheckRequest) bool { return in.TupleKey.Relation == "deny_get" }
+	isAllow := func(in *openfgav1.CheckRequest) bool { return in.TupleKey.Relation == "get" }
+
+	testCases := []struct {
+		name     string
+		fgaMocks func(openfga *mocks.OpenFGAServiceClient)
+		res      authorization.Response
+	}{
+		{
+			name: "should deny if the deny relation is satisfied",
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isDeny)).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+			res: authorization.DeniedWithReason("denied by relation deny_get on core_pod:a/web-0"),
+		},
+		{
+			name: "should check the relation if the deny relation is not satisfied",
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isDeny)).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isAllow)).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+			res: authorization.Allowed(),
+		},
+		{
+			name: "should check the relation if the deny relation is not defined",
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isDeny)).Return(nil, status.Error(codes.InvalidArgument, "relation not found"))
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isAllow)).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+			res: authorization.Allowed(),
+		},
+		{
+			name: "should return no opinion if the deny check fails",
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(isDeny)).Return(nil, status.Error(codes.Unavailable, "unavailable"))
+			},
+			res: authorization.NoOpinion(),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Version: "v1"}
+			rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+				StoreID:         "store-id",
+				RESTMapper:      rm,
+				AccountName:     "origin-account",
+				ParentClusterID: "origin",
+			}, true)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			test.fgaMocks(openfga)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithDenyRelation("deny_%s"))
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "alice",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Version:   "v1",
+						Resource:  "pods",
+						Verb:      "get",
+						Namespace: "test-ns",
+						Name:      "web-0",
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestHandler_GroupTuples(t *testing.T) {
+	groupModel := openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id: "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{Type: "group", Relations: map[string]*openfgav1.Userset{"member": {}}},
+		},
+	})
+	legacyModel := openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id:              "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	})
+
+	manyGroups := make([]string, 150)
+	for i := range manyGroups {
+		manyGroups[i] = fmt.Sprintf("team-%d", i)
+	}
+
+	testCases := []struct {
+		name       string
+		model      *openfga.Model
+		groups     []string
+		wantGroups int
+	}{
+		{
+			name:       "should send groups if the model defines group members",
+			model:      groupModel,
+			groups:     []string{"admins", "developers"},
+			wantGroups: 2,
+		},
+		{
+			name:   "should not send groups if the model does not define group members",
+			model:  legacyModel,
+			groups: []string{"admins", "developers"},
+		},
+		{
+			name:       "should drop groups exceeding the contextual tuple limit",
+			model:      groupModel,
+			groups:     manyGroups,
+			wantGroups: openfga.MaxContextualTuples - 2,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Version: "v1"}
+			rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+				StoreID:              "store-id",
+				AuthorizationModelID: "model-1",
+				RESTMapper:           rm,
+				AccountName:          "origin-account",
+				ParentClusterID:      "origin",
+			}, true)
+
+			models := mocks.NewModelProvider(t)
+			models.EXPECT().Model(mock.Anything, "store-id", "model-1").Return(test.model, nil)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+					tuples := in.GetContextualTuples().GetTupleKeys()
+					assert.LessOrEqual(t, len(tuples), 100)
+
+					groups := 0
+					for _, tuple := range tuples {
+						if tuple.Relation == "member" {
+							groups++
+						}
+					}
+					assert.Equal(t, test.wantGroups, groups)
+					return &openfgav1.CheckResponse{Allowed: true}, nil
+				},
+			)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			groups, err := identity.NewGroupFilter([]string{"*"}, nil)
+			assert.NoError(t, err)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second,
+				contextual.WithAuthorizationModels(models),
+				contextual.WithGroups(groups),
+			)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User:   "alice",
+					Groups: test.groups,
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Version:   "v1",
+						Resource:  "pods",
+						Verb:      "get",
+						Namespace: "test-ns",
+						Name:      "web-0",
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			assert.Equal(t, authorization.Allowed(), h.Handle(t.Context(), req))
+		})
+	}
+}
+
+func TestHandler_ServiceAccountFallback(t *testing.T) {
+	rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	gv := schema.GroupVersion{Version: "v1"}
+	rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+
+	cc := mocks.NewClusterCacheProvider(t)
+	cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+		StoreID:              "store-id",
+		AuthorizationModelID: "model-1",
+		RESTMapper:           rm,
+		AccountName:          "origin-account",
+		ParentClusterID:      "origin",
+	}, true)
+
+	// the model predates service account types, relations are not validated
+	models := mocks.NewModelProvider(t)
+	models.EXPECT().Model(mock.Anything, "store-id", "model-1").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id:              "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	}), nil)
+
+	openfga := mocks.NewOpenFGAServiceClient(t)
+	openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+		func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+			assert.Equal(t, "user:system:serviceaccount:test-ns:builder", in.TupleKey.User)
+			assert.False(t, slices.ContainsFunc(in.GetContextualTuples().GetTupleKeys(), func(tk *openfgav1.TupleKey) bool {
+				return strings.HasPrefix(tk.Object, "core_serviceaccount:")
+			}))
+			return &openfgav1.CheckResponse{Allowed: true}, nil
+		},
+	)
+
+	cacheMissTracker := mocks.NewTracker[string](t)
+
+	h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithAuthorizationModels(models))
+
+	req, err := authorization.NewRequest(v1.SubjectAccessReview{
+		Spec: v1.SubjectAccessReviewSpec{
+			User: "system:serviceaccount:test-ns:builder",
+			Extra: map[string]v1.ExtraValue{
+				"authorization.kubernetes.io/cluster-name": {"a"},
+			},
+			ResourceAttributes: &v1.ResourceAttributes{
+				Version:   "v1",
+				Resource:  "pods",
+				Verb:      "get",
+				Namespace: "test-ns",
+				Name:      "web-0",
+			},
+		},
+	}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, authorization.Allowed(), h.Handle(t.Context(), req))
+}
+
+func TestHandler_OwnerReferences(t *testing.T) {
+	replicaSet := schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	owners := map[string][]metav1.OwnerReference{
+		"web-0": {
+			{APIVersion: "apps/v1/beta", Kind: "ReplicaSet", Name: "invalid"},
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-abc"},
+		},
+		"web-abc": {{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+	}
+
+	testCases := []struct {
+		name         string
+		verb         string
+		opts         []contextual.Option
+		clientMocks  func(cl *mocks.Client)
+		expectClient bool
+		tuples       []string
+	}{
+		{
+			name:         "should parent the object to its owners up to the maximum depth",
+			verb:         "get",
+			opts:         []contextual.Option{contextual.WithOwnerReferences(2, replicaSet, deployment)},
+			expectClient: true,
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+				"core_pod:a/web-0#parent@apps_replicaset:a/web-abc",
+				"apps_replicaset:a/web-abc#parent@core_namespace:a/test-ns",
+				"apps_replicaset:a/web-abc#parent@apps_deployment:a/web",
+				"apps_deployment:a/web#parent@core_namespace:a/test-ns",
+			},
+		},
+		{
+			name:         "should stop following owner references at the maximum depth",
+			verb:         "delete",
+			opts:         []contextual.Option{contextual.WithOwnerReferences(1, replicaSet, deployment)},
+			expectClient: true,
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+				"core_pod:a/web-0#parent@apps_replicaset:a/web-abc",
+				"apps_replicaset:a/web-abc#parent@core_namespace:a/test-ns",
+			},
+		},
+		{
+			name:         "should only follow owners of allowed kinds",
+			verb:         "get",
+			opts:         []contextual.Option{contextual.WithOwnerReferences(2, deployment)},
+			expectClient: true,
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+			},
+		},
+		{
+			name: "should ignore owners that cannot be read",
+			verb: "get",
+			opts: []contextual.Option{contextual.WithOwnerReferences(2, replicaSet, deployment)},
+			clientMocks: func(cl *mocks.Client) {
+				cl.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("forbidden"))
+			},
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+			},
+		},
+		{
+			name: "should not follow owner references for other verbs",
+			verb: "escalate",
+			opts: []contextual.Option{contextual.WithOwnerReferences(2, replicaSet, deployment)},
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+			},
+		},
+		{
+			name: "should not follow owner references if disabled",
+			verb: "get",
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			core := schema.GroupVersion{Version: "v1"}
+			apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+			rm.AddSpecific(core.WithKind("Pod"), core.WithResource("pods"), core.WithResource("pod"), meta.RESTScopeNamespace)
+			rm.AddSpecific(apps.WithKind("ReplicaSet"), apps.WithResource("replicasets"), apps.WithResource("replicaset"), meta.RESTScopeNamespace)
+			rm.AddSpecific(apps.WithKind("Deployment"), apps.WithResource("deployments"), apps.WithResource("deployment"), meta.RESTScopeNamespace)
+
+			cl := mocks.NewClient(t)
+			if test.clientMocks != nil {
+				test.clientMocks(cl)
+			} else if test.expectClient {
+				cl.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).
+					Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) {
+						assert.Equal(t, "test-ns", key.Namespace)
+						obj.SetOwnerReferences(owners[key.Name])
+					}).
+					Return(nil)
+			}
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+				StoreID:         "store-id",
+				RESTMapper:      rm,
+				AccountName:     "origin-account",
+				ParentClusterID: "origin",
+				Client:          cl,
+			}, true)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+					var tuples []string
+					for _, tk := range in.GetContextualTuples().GetTupleKeys() {
+						tuples = append(tuples, fmt.Sprintf("%s#%s@%s", tk.Object, tk.Relation, tk.User))
+					}
+					assert.Equal(t, test.tuples, tuples)
+
+					return &openfgav1.CheckResponse{Allowed: true}, nil
+				},
+			)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, test.opts...)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "alice",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Version:   "v1",
+						Resource:  "pods",
+						Verb:      test.verb,
+						Namespace: "test-ns",
+						Name:      "web-0",
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, authorization.Allowed(), res)
+		})
+	}
+}
+
+func TestHandler_TypeCollisions(t *testing.T) {
+	rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	gv := schema.GroupVersion{Version: "v1"}
+	rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+
+	types := util.NewTypeRegistry(util.TypeNamingTruncate, util.MaxRelationLength)
+
+	cc := mocks.NewClusterCacheProvider(t)
+	cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+		StoreID:         "store-id",
+		RESTMapper:      rm,
+		AccountName:     "origin-account",
+		ParentClusterID: "origin",
+		Types:           types,
+	}, true)
+
+	openfga := mocks.NewOpenFGAServiceClient(t)
+	openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+
+	h := contextual.New(openfga, cc, mocks.NewTracker[string](t), time.Second)
+
+	req, err := authorization.NewRequest(v1.SubjectAccessReview{
+		Spec: v1.SubjectAccessReviewSpec{
+			User: "alice",
+			Extra: map[string]v1.ExtraValue{
+				"authorization.kubernetes.io/cluster-name": {"a"},
+			},
+			ResourceAttributes: &v1.ResourceAttributes{
+				Version:   "v1",
+				Resource:  "pods",
+				Verb:      "get",
+				Namespace: "test-ns",
+				Name:      "web-0",
+			},
+		},
+	}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, authorization.Allowed(), h.Handle(t.Context(), req))
+
+	// the checked resource was registered with its object type
+	collision, collides := types.Register(schema.GroupVersionResource{Group: "core", Version: "v1", Resource: "pods"}, "pod")
+	assert.True(t, collides)
+	assert.Equal(t, util.TypeCollision{
+		ObjectType: "core_pod",
+		Existing:   schema.GroupResource{Resource: "pods"},
+		Colliding:  schema.GroupResource{Group: "core", Resource: "pods"},
+	}, collision)
+}
+
+func TestParseUnknownVerbPolicy(t *testing.T) {
+	policy, err := contextual.ParseUnknownVerbPolicy("skip")
+	assert.NoError(t, err)
+	assert.Equal(t, contextual.UnknownVerbSkip, policy)
+
+	_, err = contextual.ParseUnknownVerbPolicy("deny")
+	assert.Error(t, err)
+}
+
+func TestHandler_Mappings(t *testing.T) {
+	mappings, err := mapping.New(mapping.Config{
+		Resources: []mapping.Rule{
+			{
+				Resource: "secrets",
+				Object:   "core_secret:{{.ClusterName}}/{{.Namespace}}/{{.Name}}",
+				Relation: "{{if eq .Verb \"get\"}}read{{else}}{{.Verb}}{{end}}",
+				Tuples: []mapping.TupleRule{
+					{Object: "core_secret:{{.ClusterName}}/{{.Namespace}}/{{.Name}}", Relation: "cluster", User: "kcp_cluster:{{.ClusterName}}"},
+				},
+			},
+			{
+				Resource:           "configmaps",
+				CollectionRelation: "{{.Verb}}_configs",
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		resource string
+		verb     string
+		objName  string
+		object   string
+		relation string
+		tuples   []string
+	}{
+		{
+			name:     "should render the object and relation of an override",
+			resource: "secrets",
+			verb:     "get",
+			objName:  "token",
+			object:   "core_secret:a/test-ns/token",
+			relation: "read",
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_secret:a/test-ns/token#parent@core_namespace:a/test-ns",
+				"core_secret:a/test-ns/token#cluster@kcp_cluster:a",
+			},
+		},
+		{
+			name:     "should inherit the default templates in an override",
+			resource: "configmaps",
+			verb:     "create",
+			object:   "core_namespace:a/test-ns",
+			relation: "create_configs",
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+			},
+		},
+		{
+			name:     "should use the default mapping without override",
+			resource: "pods",
+			verb:     "get",
+			objName:  "web-0",
+			object:   "core_pod:a/web-0",
+			relation: "get",
+			tuples: []string{
+				"core_namespace:a/test-ns#parent@core_platform-mesh_io_account:origin/origin-account",
+				"core_pod:a/web-0#parent@core_namespace:a/test-ns",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rm := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Version: "v1"}
+			rm.AddSpecific(gv.WithKind("Pod"), gv.WithResource("pods"), gv.WithResource("pod"), meta.RESTScopeNamespace)
+			rm.AddSpecific(gv.WithKind("Secret"), gv.WithResource("secrets"), gv.WithResource("secret"), meta.RESTScopeNamespace)
+			rm.AddSpecific(gv.WithKind("ConfigMap"), gv.WithResource("configmaps"), gv.WithResource("configmap"), meta.RESTScopeNamespace)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{
+				StoreID:         "store-id",
+				RESTMapper:      rm,
+				AccountName:     "origin-account",
+				ParentClusterID: "origin",
+			}, true)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+					assert.Equal(t, test.object, in.TupleKey.Object)
+					assert.Equal(t, test.relation, in.TupleKey.Relation)
+
+					var tuples []string
+					for _, tk := range in.GetContextualTuples().GetTupleKeys() {
+						tuples = append(tuples, fmt.Sprintf("%s#%s@%s", tk.Object, tk.Relation, tk.User))
+					}
+					assert.Equal(t, test.tuples, tuples)
+
+					return &openfgav1.CheckResponse{Allowed: true}, nil
+				},
+			)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithMappings(mappings))
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "alice",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Version:   "v1",
+						Resource:  test.resource,
+						Verb:      test.verb,
+						Namespace: "test-ns",
+						Name:      test.objName,
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, authorization.Allowed(), res)
+		})
+	}
+}
+
+func TestHandler_PermissionClaims(t *testing.T) {
+	const (
+		bindTuple   = "core_platform-mesh_io_account:consumer-parent/consumer-account#bind@apis_kcp_io_apiexport:provider-cluster-id/test-export"
+		getTuple    = "core_platform-mesh_io_account:consumer-parent/consumer-account#get_core_configmaps@user:alice"
+		listTuple   = "core_platform-mesh_io_account:consumer-parent/consumer-account#list_core_configmaps@user:alice"
+		deleteTuple = "core_platform-mesh_io_account:consumer-parent/consumer-account#delete_core_configmaps@user:alice"
+	)
+
+	claims := []any{
+		map[string]any{"group": "", "resource": "configmaps", "verbs": []any{"get", "list"}},
+	}
+
+	testCases := []struct {
+		name    string
+		policy  contextual.PermissionClaimPolicy
+		claims  []any
+		granted []string
+		getErr  error
+		readErr error
+		res     authorization.Response
+	}{
+		{
+			name:    "should allow if the user may grant every claim",
+			policy:  contextual.PermissionClaimsDeny,
+			claims:  claims,
+			granted: []string{bindTuple, getTuple, listTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should deny if the user may not grant a claim",
+			policy:  contextual.PermissionClaimsDeny,
+			claims:  claims,
+			granted: []string{bindTuple, getTuple},
+			res:     authorization.DeniedWithReason(`user "alice" may not grant permission claim list on configmaps`),
+		},
+		{
+			name:    "should return no opinion if the user may not grant a claim",
+			policy:  contextual.PermissionClaimsNoOpinion,
+			claims:  claims,
+			granted: []string{bindTuple},
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should expand claims of all verbs",
+			policy:  contextual.PermissionClaimsDeny,
+			claims:  []any{map[string]any{"group": "", "resource": "configmaps", "verbs": []any{"*"}}},
+			granted: []string{bindTuple, getTuple, listTuple, deleteTuple},
+			res:     authorization.DeniedWithReason(`user "alice" may not grant permission claim watch on configmaps`),
+		},
+		{
+			name:    "should allow exports without claims",
+			policy:  contextual.PermissionClaimsDeny,
+			granted: []string{bindTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should return no opinion if the export cannot be read",
+			policy:  contextual.PermissionClaimsDeny,
+			granted: []string{bindTuple},
+			readErr: errors.New("connection refused"),
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should allow if the export does not exist",
+			policy:  contextual.PermissionClaimsDeny,
+			granted: []string{bindTuple},
+			readErr: apierrors.NewNotFound(schema.GroupResource{Group: "apis.kcp.io", Resource: "apiexports"}, "test-export"),
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should return no opinion if the export may not be read",
+			policy:  contextual.PermissionClaimsDeny,
+			granted: []string{bindTuple},
+			readErr: apierrors.NewForbidden(schema.GroupResource{Group: "apis.kcp.io", Resource: "apiexports"}, "test-export", errors.New("no access")),
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should return no opinion if the provider cluster cannot be reached",
+			policy:  contextual.PermissionClaimsDeny,
+			granted: []string{bindTuple},
+			getErr:  errors.New("cluster not found"),
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should not check claims if ignored",
+			policy:  contextual.PermissionClaimsIgnore,
+			granted: []string{bindTuple},
+			res:     authorization.Allowed(),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			consumerRM := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Group: "apis.kcp.io", Version: "v1alpha1"}
+			consumerRM.AddSpecific(gv.WithKind("APIExport"), gv.WithResource("apiexports"), gv.WithResource("apiexport"), meta.RESTScopeRoot)
+
+			providers := mocks.NewManager(t)
+			if test.policy != contextual.PermissionClaimsIgnore {
+				if test.getErr != nil {
+					providers.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("provider-cluster-id")).Return(nil, test.getErr)
+				} else {
+					providerClient := mocks.NewClient(t)
+					providerClient.EXPECT().Get(mock.Anything, client.ObjectKey{Name: "test-export"}, mock.Anything).
+						RunAndReturn(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+							if test.claims != nil {
+								obj.(*unstructured.Unstructured).Object["spec"] = map[string]any{"permissionClaims": test.claims}
+							}
+							return test.readErr
+						})
+					provider := mocks.NewCluster(t)
+					// the export is read through the cached client of the provider
+					// cluster, the mocked cluster fails on live reads via GetAPIReader
+					provider.EXPECT().GetClient().Return(providerClient)
+					providers.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("provider-cluster-id")).Return(provider, nil)
+				}
+			}
+
+			// the provider cluster is not required in the cache
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.ClusterInfo{
+				StoreID:         "consumer-store-id",
+				RESTMapper:      consumerRM,
+				AccountName:     "consumer-account",
+				ParentClusterID: "consumer-parent",
+			}, true)
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+					assert.Equal(t, "consumer-store-id", in.StoreId)
+					tuple := fmt.Sprintf("%s#%s@%s", in.TupleKey.Object, in.TupleKey.Relation, in.TupleKey.User)
+					return &openfgav1.CheckResponse{Allowed: slices.Contains(test.granted, tuple)}, nil
+				},
+			)
+			openfga.EXPECT().BatchCheck(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.BatchCheckRequest, opts ...grpc.CallOption) (*openfgav1.BatchCheckResponse, error) {
+					assert.Equal(t, "consumer-store-id", in.StoreId)
+					result := map[string]*openfgav1.BatchCheckSingleResult{}
+					for _, item := range in.Checks {
+						tuple := fmt.Sprintf("%s#%s@%s", item.TupleKey.Object, item.TupleKey.Relation, item.TupleKey.User)
+						result[item.CorrelationId] = &openfgav1.BatchCheckSingleResult{
+							CheckResult: &openfgav1.BatchCheckSingleResult_Allowed{Allowed: slices.Contains(test.granted, tuple)},
+						}
+					}
+					return &openfgav1.BatchCheckResponse{Result: result}, nil
+				},
+			).Maybe()
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithPermissionClaims(test.policy, providers))
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User:   "alice",
+					Groups: []string{"system:authenticated", "system:cluster:consumer-cluster-id"},
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"provider-cluster-id"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Group:    "apis.kcp.io",
+						Version:  "v1alpha1",
+						Resource: "apiexports",
+						Verb:     "bind",
+						Name:     "test-export",
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestHandler_BindUser(t *testing.T) {
+	const (
+		exportTuple = "core_platform-mesh_io_account:consumer-parent/consumer-account#bind@apis_kcp_io_apiexport:provider-cluster-id/test-export"
+		userTuple   = "core_platform-mesh_io_account:consumer-parent/consumer-account#create_apis_kcp_io_apibindings@user:alice"
+	)
+
+	testCases := []struct {
+		name    string
+		policy  contextual.BindUserPolicy
+		granted []string
+		res     authorization.Response
+	}{
+		{
+			name:    "should allow if export and user may bind",
+			policy:  contextual.BindUserAnd,
+			granted: []string{exportTuple, userTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should return no opinion if only the export may bind",
+			policy:  contextual.BindUserAnd,
+			granted: []string{exportTuple},
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should not check the user if the export may not bind",
+			policy:  contextual.BindUserAnd,
+			granted: []string{userTuple},
+			res:     authorization.NoOpinion(),
+		},
+		{
+			name:    "should allow if only the user may bind",
+			policy:  contextual.BindUserOr,
+			granted: []string{userTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should allow if only the export may bind",
+			policy:  contextual.BindUserOr,
+			granted: []string{exportTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:   "should return no opinion if neither may bind",
+			policy: contextual.BindUserOr,
+			res:    authorization.NoOpinion(),
+		},
+		{
+			name:    "should not require the provider cluster in the cache",
+			policy:  contextual.BindUserAnd,
+			granted: []string{exportTuple, userTuple},
+			res:     authorization.Allowed(),
+		},
+		{
+			name:    "should not check the user if ignored",
+			policy:  contextual.BindUserIgnore,
+			granted: []string{exportTuple},
+			res:     authorization.Allowed(),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			consumerRM := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+			gv := schema.GroupVersion{Group: "apis.kcp.io", Version: "v1alpha1"}
+			consumerRM.AddSpecific(gv.WithKind("APIExport"), gv.WithResource("apiexports"), gv.WithResource("apiexport"), meta.RESTScopeRoot)
+
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.ClusterInfo{
+				StoreID:         "consumer-store-id",
+				RESTMapper:      consumerRM,
+				AccountName:     "consumer-account",
+				ParentClusterID: "consumer-parent",
+			}, true)
+
+			var checked []string
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+					assert.Equal(t, "consumer-store-id", in.StoreId)
+					tuple := fmt.Sprintf("%s#%s@%s", in.TupleKey.Object, in.TupleKey.Relation, in.TupleKey.User)
+					checked = append(checked, tuple)
+					return &openfgav1.CheckResponse{Allowed: slices.Contains(test.granted, tuple)}, nil
+				},
+			)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+
+			h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithBindUser(test.policy))
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User:   "alice",
+					Groups: []string{"system:authenticated", "system:cluster:consumer-cluster-id"},
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"provider-cluster-id"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Group:    "apis.kcp.io",
+						Version:  "v1alpha1",
+						Resource: "apiexports",
+						Verb:     "bind",
+						Name:     "test-export",
+					},
+				},
+			}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+
+			assert.Equal(t, test.res, res)
+			assert.Equal(t, exportTuple, checked[0])
+		})
+	}
+}
+
+func TestHandler_BindAccountMapping(t *testing.T) {
+	mappings, err := mapping.New(mapping.Config{
+		Default: mapping.Rule{Account: "core_platform-mesh_io_account:{{.ClusterName}}"},
+	})
+	assert.NoError(t, err)
+
+	consumerRM := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	gv := schema.GroupVersion{Group: "apis.kcp.io", Version: "v1alpha1"}
+	consumerRM.AddSpecific(gv.WithKind("APIExport"), gv.WithResource("apiexports"), gv.WithResource("apiexport"), meta.RESTScopeRoot)
+
+	cc := mocks.NewClusterCacheProvider(t)
+	cc.EXPECT().Get(multicluster.ClusterName("consumer-cluster-id")).Return(clustercache.ClusterInfo{
+		StoreID:         "consumer-store-id",
+		RESTMapper:      consumerRM,
+		AccountName:     "consumer-account",
+		ParentClusterID: "consumer-parent",
+	}, true)
+
+	var checked []string
+	openfga := mocks.NewOpenFGAServiceClient(t)
+	openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+		func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+			checked = append(checked, fmt.Sprintf("%s#%s@%s", in.TupleKey.Object, in.TupleKey.Relation, in.TupleKey.User))
+			return &openfgav1.CheckResponse{Allowed: true}, nil
+		},
+	)
+
+	cacheMissTracker := mocks.NewTracker[string](t)
+
+	h := contextual.New(openfga, cc, cacheMissTracker, time.Second, contextual.WithMappings(mappings), contextual.WithBindUser(contextual.BindUserAnd))
+
+	req, err := authorization.NewRequest(v1.SubjectAccessReview{
+		Spec: v1.SubjectAccessReviewSpec{
+			User:   "alice",
+			Groups: []string{"system:authenticated", "system:cluster:consumer-cluster-id"},
+			Extra: map[string]v1.ExtraValue{
+				"authorization.kubernetes.io/cluster-name": {"provider-cluster-id"},
+			},
+			ResourceAttributes: &v1.ResourceAttributes{
+				Group:    "apis.kcp.io",
+				Version:  "v1alpha1",
+				Resource: "apiexports",
+				Verb:     "bind",
+				Name:     "test-export",
+			},
+		},
+	}, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+	assert.NoError(t, err)
+
+	res := h.Handle(t.Context(), req)
+
+	assert.Equal(t, authorization.Allowed(), res)
+	assert.Equal(t, []string{
+		"core_platform-mesh_io_account:consumer-cluster-id#bind@apis_kcp_io_apiexport:provider-cluster-id/test-export",
+		"core_platform-mesh_io_account:consumer-cluster-id#create_apis_kcp_io_apibindings@user:alice",
+	}, checked)
 }
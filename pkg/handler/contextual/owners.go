@@ -0,0 +1,152 @@
+package contextual
+
+import (
+	"context"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// ownerVerbs are the verbs on named objects that are also granted through
+// the owners of the object.
+var ownerVerbs = map[string]bool{
+	"get":    true,
+	"update": true,
+	"patch":  true,
+	"delete": true,
+}
+
+// ownerReadTimeout bounds reading the owners of an object, as the first read
+// of a kind waits for its metadata informer to sync.
+const ownerReadTimeout = 2 * time.Second
+
+// owner is an object referenced by ownerReferences, resolved to its OpenFGA object.
+type owner struct {
+	gvk    schema.GroupVersionKind
+	name   string
+	object string
+}
+
+// ownerTuples returns parentRelation tuples from a namespaced object to its
+// owners and from the owners to namespaceObject, following owner references of
+// allowed kinds up to the configured depth. Owners that cannot be read end
+// the chain without failing the check.
+func (c *contextualAuthorizer) ownerTuples(ctx context.Context, info clustercache.ClusterInfo, clusterName string, gvk schema.GroupVersionKind, namespace, name, object, namespaceObject, parentRelation string) []*openfgav1.TupleKey {
+	if info.Client == nil {
+		return nil
+	}
+
+	var tuples []*openfgav1.TupleKey
+	visited := map[string]bool{object: true}
+	current := []owner{{gvk: gvk, name: name, object: object}}
+
+	for depth := 0; depth < c.ownerMaxDepth && len(current) > 0; depth++ {
+		var next []owner
+		for _, child := range current {
+			owners, err := c.readOwners(ctx, info, clusterName, child.gvk, namespace, child.name)
+			if err != nil {
+				klog.V(5).ErrorS(err, "failed to resolve owners, skipping", "GVK", child.gvk, "namespace", namespace, "name", child.name)
+				continue
+			}
+
+			for _, o := range owners {
+				tuples = append(tuples, &openfgav1.TupleKey{
+					Object:   child.object,
+					Relation: parentRelation,
+					User:     o.object,
+				})
+				if visited[o.object] {
+					continue
+				}
+				visited[o.object] = true
+
+				// parent the owner to the namespace
+				tuples = append(tuples, &openfgav1.TupleKey{
+					Object:   o.object,
+					Relation: parentRelation,
+					User:     namespaceObject,
+				})
+				next = append(next, o)
+			}
+		}
+		current = next
+	}
+
+	return tuples
+}
+
+// readOwners reads the owner references of an object from the metadata cache
+// of the cluster and returns the owners of allowed kinds.
+func (c *contextualAuthorizer) readOwners(ctx context.Context, info clustercache.ClusterInfo, clusterName string, gvk schema.GroupVersionKind, namespace, name string) ([]owner, error) {
+	ctx, cancel := context.WithTimeout(ctx, ownerReadTimeout)
+	defer cancel()
+
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	if err := info.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, err
+	}
+
+	var owners []owner
+	for _, ref := range obj.GetOwnerReferences() {
+		o, ok, err := c.owner(info, clusterName, namespace, ref)
+		if err != nil {
+			klog.V(5).ErrorS(err, "failed to resolve owner reference, skipping", "apiVersion", ref.APIVersion, "kind", ref.Kind, "namespace", namespace, "name", ref.Name)
+			continue
+		}
+		if ok {
+			owners = append(owners, o)
+		}
+	}
+
+	return owners, nil
+}
+
+// owner resolves an owner reference to its OpenFGA object. It returns false
+// if the owner is not of an allowed kind.
+func (c *contextualAuthorizer) owner(info clustercache.ClusterInfo, clusterName, namespace string, ref metav1.OwnerReference) (owner, bool, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return owner{}, false, err
+	}
+	ownerGVK := gv.WithKind(ref.Kind)
+	if !c.ownerKinds[ownerGVK.GroupKind()] {
+		return owner{}, false, nil
+	}
+
+	restMapping, err := info.RESTMapper.RESTMapping(ownerGVK.GroupKind(), ownerGVK.Version)
+	if err != nil {
+		return owner{}, false, err
+	}
+
+	singular, err := info.RESTMapper.ResourceSingularizer(restMapping.Resource.Resource)
+	if err != nil {
+		return owner{}, false, err
+	}
+
+	group, objectType := c.objectType(info, restMapping.Resource, singular)
+	object, err := c.mappings.Object(mapping.Attributes{
+		APIGroup:        restMapping.Resource.Group,
+		Group:           group,
+		Version:         restMapping.Resource.Version,
+		Resource:        restMapping.Resource.Resource,
+		Singular:        singular,
+		ObjectType:      objectType,
+		Namespace:       namespace,
+		Name:            ref.Name,
+		ClusterName:     clusterName,
+		AccountName:     info.AccountName,
+		ParentClusterID: info.ParentClusterID,
+	})
+	if err != nil {
+		return owner{}, false, err
+	}
+	return owner{gvk: ownerGVK, name: ref.Name, object: object}, true, nil
+}
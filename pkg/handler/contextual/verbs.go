@@ -0,0 +1,88 @@
+package contextual
+
+import "fmt"
+
+// verbScope describes how a verb is checked in OpenFGA.
+type verbScope int
+
+const (
+	// scopeObject checks the verb as relation on the named object.
+	scopeObject verbScope = iota
+	// scopeParent checks <verb>_<group>_<resource> on the namespace or, for
+	// cluster scoped resources and requests across all namespaces, the account.
+	scopeParent
+	// scopeSkip leaves the decision to other handlers.
+	scopeSkip
+)
+
+// verbScopes covers every verb used by Kubernetes and kcp authorizers.
+var verbScopes = map[string]verbScope{
+	"get":    scopeObject,
+	"update": scopeObject,
+	"patch":  scopeObject,
+	"delete": scopeObject,
+
+	"create":           scopeParent,
+	"list":             scopeParent,
+	"watch":            scopeParent,
+	"deletecollection": scopeParent,
+
+	// RBAC checks bind and escalate on the role or cluster role being bound
+	// or modified, certificates check approve and sign on the signer name.
+	// The rules of the role are not evaluated: RBAC only asks for bind and
+	// escalate if the user does not hold all permissions of the role, so
+	// allowing them is the decision of the model.
+	"bind":     scopeObject,
+	"escalate": scopeObject,
+	"approve":  scopeObject,
+	"sign":     scopeObject,
+	"use":      scopeObject,
+
+	// Impersonation targets users, groups and service accounts, which are
+	// not objects of the cluster.
+	"impersonate": scopeSkip,
+}
+
+// UnknownVerbPolicy configures how verbs missing from the verb table are checked.
+type UnknownVerbPolicy string
+
+const (
+	// UnknownVerbObject checks unknown verbs on the named object.
+	UnknownVerbObject UnknownVerbPolicy = "object"
+	// UnknownVerbParent checks unknown verbs on the namespace or account.
+	UnknownVerbParent UnknownVerbPolicy = "parent"
+	// UnknownVerbSkip returns no opinion for unknown verbs.
+	UnknownVerbSkip UnknownVerbPolicy = "skip"
+)
+
+// ParseUnknownVerbPolicy validates policy.
+func ParseUnknownVerbPolicy(policy string) (UnknownVerbPolicy, error) {
+	switch p := UnknownVerbPolicy(policy); p {
+	case UnknownVerbObject, UnknownVerbParent, UnknownVerbSkip:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown verb policy %q, expected one of %q, %q or %q", policy, UnknownVerbObject, UnknownVerbParent, UnknownVerbSkip)
+	}
+}
+
+func (p UnknownVerbPolicy) scope() verbScope {
+	switch p {
+	case UnknownVerbParent:
+		return scopeParent
+	case UnknownVerbSkip:
+		return scopeSkip
+	default:
+		return scopeObject
+	}
+}
+
+// resolveVerb returns the relation verb after applying aliases and how it is checked.
+func (c *contextualAuthorizer) resolveVerb(verb string) (string, verbScope) {
+	if alias, ok := c.verbAliases[verb]; ok {
+		verb = alias
+	}
+	if scope, ok := verbScopes[verb]; ok {
+		return verb, scope
+	}
+	return verb, c.unknownVerbPolicy.scope()
+}
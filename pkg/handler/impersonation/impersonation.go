@@ -0,0 +1,272 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/retry"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const (
+	handlerName         = "impersonation"
+	impersonateVerb     = "impersonate"
+	authenticationGroup = "authentication.k8s.io"
+)
+
+type impersonationAuthorizer struct {
+	fga          openfgav1.OpenFGAServiceClient
+	clusterCache clustercache.Provider
+
+	identities        *identity.Mapper
+	models            openfga.ModelProvider
+	validateRelations bool
+	consistency       openfga.ConsistencyPolicy
+
+	// denyRelationFormat derives the deny relation checked before the
+	// impersonate relation, e.g. deny_%s. Deny relations are not checked if
+	// empty.
+	denyRelationFormat string
+	// checkContext builds the context OpenFGA conditions are evaluated against.
+	checkContext *openfga.ContextBuilder
+	// groups selects the request groups sent as contextual group memberships.
+	groups identity.GroupFilter
+
+	cacheMissTracker    retry.Tracker[string]
+	cacheMissRetryAfter time.Duration
+
+	// checker resolves models, validates relations and checks deny
+	// relations as configured by the options above. It is built in New.
+	checker *openfga.Checker
+}
+
+var _ authorization.Handler = &impersonationAuthorizer{}
+
+// Option configures optional behavior of the impersonation authorizer.
+type Option func(*impersonationAuthorizer)
+
+// WithIdentityMapper configures how the caller and impersonated users are
+// mapped to OpenFGA users.
+func WithIdentityMapper(identities *identity.Mapper) Option {
+	return func(i *impersonationAuthorizer) {
+		i.identities = identities
+	}
+}
+
+// WithAuthorizationModels configures the provider resolving the authorization
+// model ID sent along with every check.
+func WithAuthorizationModels(models openfga.ModelProvider) Option {
+	return func(i *impersonationAuthorizer) {
+		i.models = models
+	}
+}
+
+// WithRelationValidation skips checks whose object type or relation is not
+// defined in the store's authorization model. It requires WithAuthorizationModels.
+func WithRelationValidation(enabled bool) Option {
+	return func(i *impersonationAuthorizer) {
+		i.validateRelations = enabled
+	}
+}
+
+// WithConsistency configures the consistency preference of checks per verb.
+func WithConsistency(consistency openfga.ConsistencyPolicy) Option {
+	return func(i *impersonationAuthorizer) {
+		i.consistency = consistency
+	}
+}
+
+// WithDenyRelation checks the deny relation derived from format, e.g.
+// deny_impersonate for deny_%s, before every check and denies the request if
+// it is satisfied. Without WithRelationValidation, this is an additional
+// check for every request, even if the model does not define the deny
+// relation.
+func WithDenyRelation(format string) Option {
+	return func(i *impersonationAuthorizer) {
+		i.denyRelationFormat = format
+	}
+}
+
+// WithCheckContext passes the request attributes built by builder as
+// context to OpenFGA conditions.
+func WithCheckContext(builder *openfga.ContextBuilder) Option {
+	return func(i *impersonationAuthorizer) {
+		i.checkContext = builder
+	}
+}
+
+// WithGroups sends the groups of the caller selected by filter as contextual
+// group memberships.
+func WithGroups(filter identity.GroupFilter) Option {
+	return func(i *impersonationAuthorizer) {
+		i.groups = filter
+	}
+}
+
+// WithCacheMissRetry retries requests for clusters which are not yet engaged
+// after retryAfter, as long as tracker allows it.
+func WithCacheMissRetry(tracker retry.Tracker[string], retryAfter time.Duration) Option {
+	return func(i *impersonationAuthorizer) {
+		i.cacheMissTracker = tracker
+		i.cacheMissRetryAfter = retryAfter
+	}
+}
+
+// New returns a handler checking impersonation requests as impersonate
+// relation on the impersonated identity in the store of the cluster's account.
+func New(fga openfgav1.OpenFGAServiceClient, clusterCache clustercache.Provider, opts ...Option) authorization.Handler {
+	i := &impersonationAuthorizer{
+		fga:          fga,
+		clusterCache: clusterCache,
+		identities:   identity.DefaultMapper(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.checker = openfga.NewChecker(handlerName, i.fga, i.models, i.validateRelations, i.denyRelationFormat)
+	return i
+}
+
+func (i *impersonationAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
+	klog.V(5).Info("handling request in ImpersonationAuthorizer")
+
+	attrs := req.Spec.ResourceAttributes
+	if attrs == nil || attrs.Verb != impersonateVerb {
+		klog.V(5).Info("request is not an impersonation request, skipping")
+		return authorization.NoOpinion()
+	}
+
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" && req.Attributes.ClusterPath == "" {
+		klog.V(5).Info("request does not contain a known cluster, skipping")
+		return authorization.NoOpinion()
+	}
+	if clusterName == "" {
+		// workspace paths are resolved once their cluster is engaged
+		return clustercache.CacheMiss(i.clusterCache, i.cacheMissTracker, i.cacheMissRetryAfter, req.Attributes.ClusterPath)
+	}
+
+	clusterInfo, ok := i.clusterCache.Get(multicluster.ClusterName(clusterName))
+	if !ok {
+		return clustercache.CacheMiss(i.clusterCache, i.cacheMissTracker, i.cacheMissRetryAfter, clusterName)
+	}
+
+	target, targetTuples, err := i.target(attrs, clusterName)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to map impersonated identity, skipping", "resource", attrs.Resource, "name", attrs.Name)
+		return authorization.NoOpinion()
+	}
+
+	modelID, err := i.checker.ModelID(ctx, clusterInfo.StoreID, clusterInfo.AuthorizationModelID)
+	if err != nil {
+		klog.ErrorS(err, "failed to resolve authorization model", "storeID", clusterInfo.StoreID)
+		return authorization.NoOpinion()
+	}
+
+	user, subjectTuples, err := i.checker.Subject(ctx, i.identities, req.Spec, clusterName, clusterInfo.StoreID, modelID)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to map request subject, skipping", "user", req.Spec.User)
+		return authorization.NoOpinion()
+	}
+
+	contextualTuples := append(targetTuples, subjectTuples...)
+	groupTuples, err := i.checker.GroupTuples(ctx, clusterInfo.StoreID, modelID, user, req.Spec.Groups, i.groups, len(contextualTuples))
+	if err != nil {
+		klog.ErrorS(err, "failed to load authorization model", "storeID", clusterInfo.StoreID, "modelID", modelID)
+		return authorization.NoOpinion()
+	}
+	contextualTuples = append(contextualTuples, groupTuples...)
+
+	check := &openfgav1.CheckRequest{
+		StoreId:              clusterInfo.StoreID,
+		AuthorizationModelId: modelID,
+		Consistency:          i.consistency.For(impersonateVerb),
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			Object:   target,
+			Relation: impersonateVerb,
+			User:     user,
+		},
+	}
+
+	if contextualTuples != nil {
+		check.ContextualTuples = &openfgav1.ContextualTupleKeys{
+			TupleKeys: contextualTuples,
+		}
+	}
+
+	check.Context, err = i.checkContext.Build(req.Spec, clusterName, clusterInfo.AccountName)
+	if err != nil {
+		klog.ErrorS(err, "failed to build check context")
+		return authorization.NoOpinion()
+	}
+
+	if res, ok := i.checker.Denied(ctx, check); ok {
+		return res
+	}
+
+	mapped, err := i.checker.IsMapped(ctx, check)
+	if err != nil {
+		klog.ErrorS(err, "failed to load authorization model", "storeID", check.StoreId, "modelID", modelID)
+		return authorization.NoOpinion()
+	}
+	if !mapped {
+		klog.V(2).InfoS("relation not defined in authorization model, skipping check", "object", target, "relation", impersonateVerb, "modelID", modelID)
+		metrics.RecordUnmapped(handlerName)
+		return authorization.NoOpinion()
+	}
+
+	klog.InfoS("calling fga", "object", target, "relation", impersonateVerb, "modelID", modelID)
+
+	response, err := i.fga.Check(ctx, check)
+	metrics.RecordCheck(handlerName, response.GetAllowed(), err)
+	if err != nil {
+		klog.ErrorS(err, "failed to perform OpenFGA impersonation check")
+		return authorization.NoOpinion()
+	}
+
+	klog.V(5).InfoS("performed OpenFGA impersonation check", "allowed", response.Allowed)
+
+	if response.Allowed {
+		return authorization.Allowed()
+	}
+
+	return authorization.NoOpinion()
+}
+
+// target returns the OpenFGA object of the impersonated identity and
+// contextual tuples describing it.
+func (i *impersonationAuthorizer) target(attrs *authzv1.ResourceAttributes, clusterName string) (string, []*openfgav1.TupleKey, error) {
+	if attrs.Name == "" {
+		return "", nil, fmt.Errorf("impersonation of %s without name", attrs.Resource)
+	}
+
+	switch {
+	case attrs.Group == "" && attrs.Resource == "users":
+		user, err := i.identities.User(attrs.Name)
+		return user, nil, err
+	case attrs.Group == "" && attrs.Resource == "groups":
+		return identity.GroupObject(attrs.Name), nil, nil
+	case attrs.Group == "" && attrs.Resource == "serviceaccounts":
+		if attrs.Namespace == "" {
+			return "", nil, fmt.Errorf("impersonation of service account %q without namespace", attrs.Name)
+		}
+		sa := identity.ServiceAccount{ClusterName: clusterName, Namespace: attrs.Namespace, Name: attrs.Name}
+		return sa.Object(), sa.Tuples(), nil
+	case attrs.Group == authenticationGroup && attrs.Resource == "uids":
+		return fmt.Sprintf("uid:%s", attrs.Name), nil, nil
+	case attrs.Group == authenticationGroup && attrs.Resource == "userextras" && attrs.Subresource != "":
+		return fmt.Sprintf("userextra:%s/%s", attrs.Subresource, attrs.Name), nil, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported impersonation resource %q in group %q", attrs.Resource, attrs.Group)
+	}
+}
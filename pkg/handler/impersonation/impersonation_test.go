@@ -0,0 +1,338 @@
+package impersonation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/impersonation"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	v1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+func TestHandler(t *testing.T) {
+	identities, err := identity.NewMapper(identity.MapperConfig{
+		PrefixReplacements: map[string]string{"oidc:": ""},
+		Lowercase:          true,
+	})
+	assert.NoError(t, err)
+
+	groups, err := identity.NewGroupFilter([]string{"*"}, []string{"system:*"})
+	assert.NoError(t, err)
+
+	impersonate := func(group, resource, subresource, namespace, name string) authorization.Request {
+		return authorization.Request{
+			SubjectAccessReview: v1.SubjectAccessReview{
+				Spec: v1.SubjectAccessReviewSpec{
+					User: "oidc:Bob",
+					Extra: map[string]v1.ExtraValue{
+						"authorization.kubernetes.io/cluster-name": {"a"},
+					},
+					ResourceAttributes: &v1.ResourceAttributes{
+						Verb:        "impersonate",
+						Group:       group,
+						Resource:    resource,
+						Subresource: subresource,
+						Namespace:   namespace,
+						Name:        name,
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                  string
+		req                   authorization.Request
+		res                   authorization.Response
+		opts                  []impersonation.Option
+		clusterCacheMocks     func(cc *mocks.ClusterCacheProvider)
+		cacheMissTrackerMocks func(tracker *mocks.Tracker[string])
+		fgaMocks              func(openfga *mocks.OpenFGAServiceClient)
+		modelMocks            func(models *mocks.ModelProvider)
+	}{
+		{
+			name: "should skip requests for other verbs",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "users", Name: "alice"},
+					},
+				},
+			},
+			res:               authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {},
+		},
+		{
+			name: "should skip requests without cluster",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						ResourceAttributes: &v1.ResourceAttributes{Verb: "impersonate", Resource: "users", Name: "alice"},
+					},
+				},
+			},
+			res:               authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {},
+		},
+		{
+			name: "should skip if cluster not found in cache",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateUnknown, nil)
+			},
+		},
+		{
+			name: "should retry if cluster not found in cache and cacheMissTracker returns true",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.Retry(time.Second),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateEngaging, nil)
+			},
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
+				tracker.EXPECT().ShouldRetry("a").Return(true)
+				tracker.EXPECT().Retried("a")
+			},
+		},
+		{
+			name: "should skip without retry if cluster is ignored by the cache",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateIgnored, nil)
+			},
+		},
+		{
+			name: "should check impersonation of a user with mapped identities",
+			req:  impersonate("", "users", "", "", "oidc:Alice"),
+			res:  authorization.Allowed(),
+			opts: []impersonation.Option{impersonation.WithIdentityMapper(identities)},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.StoreId == "store-id" &&
+						in.TupleKey.Object == "user:alice" &&
+						in.TupleKey.Relation == "impersonate" &&
+						in.TupleKey.User == "user:bob"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check impersonation of a group",
+			req:  impersonate("", "groups", "", "", "admins"),
+			res:  authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Object == "group:admins"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check impersonation of a service account in its namespace",
+			req:  impersonate("", "serviceaccounts", "", "default", "builder"),
+			res:  authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).RunAndReturn(
+					func(ctx context.Context, in *openfgav1.CheckRequest, opts ...grpc.CallOption) (*openfgav1.CheckResponse, error) {
+						assert.Equal(t, "core_serviceaccount:a/default/builder", in.TupleKey.Object)
+						assert.Len(t, in.ContextualTuples.TupleKeys, 1)
+						assert.Equal(t, "core_namespace:a/default", in.ContextualTuples.TupleKeys[0].User)
+
+						return &openfgav1.CheckResponse{Allowed: true}, nil
+					},
+				)
+			},
+		},
+		{
+			name: "should check impersonation of a uid",
+			req:  impersonate("authentication.k8s.io", "uids", "", "", "1234"),
+			res:  authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Object == "uid:1234"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check impersonation of extra values",
+			req:  impersonate("authentication.k8s.io", "userextras", "scopes", "", "view"),
+			res:  authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Object == "userextra:scopes/view"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should skip impersonation of service accounts without namespace",
+			req:  impersonate("", "serviceaccounts", "", "", "builder"),
+			res:  authorization.NoOpinion(),
+		},
+		{
+			name: "should skip impersonation of unknown resources",
+			req:  impersonate("", "pods", "", "default", "web-0"),
+			res:  authorization.NoOpinion(),
+		},
+		{
+			name: "should return no opinion if fga does not allow",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.NoOpinion(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+			},
+		},
+		{
+			name: "should return no opinion if fga check fails",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.NoOpinion(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+			},
+		},
+		{
+			name: "should deny if the deny relation is satisfied",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.DeniedWithReason("denied by relation deny_impersonate on user:alice"),
+			opts: []impersonation.Option{impersonation.WithDenyRelation("deny_%s")},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Object == "user:alice" &&
+						in.TupleKey.Relation == "deny_impersonate" &&
+						in.TupleKey.User == "user:oidc:Bob"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check impersonation if the deny relation is not satisfied",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.Allowed(),
+			opts: []impersonation.Option{impersonation.WithDenyRelation("deny_%s")},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "deny_impersonate"
+				})).Return(&openfgav1.CheckResponse{Allowed: false}, nil).Once()
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "impersonate"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Once()
+			},
+		},
+		{
+			name: "should send the check context and group memberships of the caller",
+			req: func() authorization.Request {
+				req := impersonate("", "users", "", "", "alice")
+				req.Spec.Groups = []string{"admins", "system:authenticated"}
+				return req
+			}(),
+			res: authorization.Allowed(),
+			opts: []impersonation.Option{
+				impersonation.WithCheckContext(openfga.NewContextBuilder()),
+				impersonation.WithGroups(groups),
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					tuples := in.GetContextualTuples().GetTupleKeys()
+					return in.GetContext().GetFields()["verb"].GetStringValue() == "impersonate" &&
+						in.GetContext().GetFields()["name"].GetStringValue() == "alice" &&
+						in.GetContext().GetFields()["cluster_name"].GetStringValue() == "a" &&
+						len(tuples) == 1 &&
+						tuples[0].Object == "group:admins" &&
+						tuples[0].Relation == "member" &&
+						tuples[0].User == "user:oidc:Bob"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should skip check for relation not defined in the authorization model",
+			req:  impersonate("", "users", "", "", "alice"),
+			res:  authorization.NoOpinion(),
+			opts: []impersonation.Option{impersonation.WithRelationValidation(true)},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "store-id", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id:              "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+				}), nil)
+			},
+		},
+		{
+			name: "should check service accounts as users if the model does not define them",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "system:serviceaccount:default:builder",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{Verb: "impersonate", Resource: "users", Name: "alice"},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.User == "user:system:serviceaccount:default:builder" &&
+						in.ContextualTuples == nil
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "store-id").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "store-id", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id:              "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+				}), nil)
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			cc := mocks.NewClusterCacheProvider(t)
+			if test.clusterCacheMocks != nil {
+				test.clusterCacheMocks(cc)
+			} else {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{StoreID: "store-id"}, true)
+			}
+
+			openfga := mocks.NewOpenFGAServiceClient(t)
+			if test.fgaMocks != nil {
+				test.fgaMocks(openfga)
+			}
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+			if test.cacheMissTrackerMocks != nil {
+				test.cacheMissTrackerMocks(cacheMissTracker)
+			} else {
+				cacheMissTracker.EXPECT().ShouldRetry(mock.Anything).Return(false).Maybe()
+			}
+
+			opts := append([]impersonation.Option{impersonation.WithCacheMissRetry(cacheMissTracker, time.Second)}, test.opts...)
+			if test.modelMocks != nil {
+				models := mocks.NewModelProvider(t)
+				test.modelMocks(models)
+				opts = append(opts, impersonation.WithAuthorizationModels(models))
+			}
+
+			h := impersonation.New(openfga, cc, opts...)
+
+			req, err := authorization.NewRequest(test.req.SubjectAccessReview, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
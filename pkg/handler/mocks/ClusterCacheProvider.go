@@ -40,6 +40,66 @@ func (_m *ClusterCacheProvider) EXPECT() *ClusterCacheProvider_Expecter {
 	return &ClusterCacheProvider_Expecter{mock: &_m.Mock}
 }
 
+// ClusterName provides a mock function for the type ClusterCacheProvider
+func (_mock *ClusterCacheProvider) ClusterName(path string) (multicluster.ClusterName, bool) {
+	ret := _mock.Called(path)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClusterName")
+	}
+
+	var r0 multicluster.ClusterName
+	var r1 bool
+	if returnFunc, ok := ret.Get(0).(func(string) (multicluster.ClusterName, bool)); ok {
+		return returnFunc(path)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) multicluster.ClusterName); ok {
+		r0 = returnFunc(path)
+	} else {
+		r0 = ret.Get(0).(multicluster.ClusterName)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = returnFunc(path)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+	return r0, r1
+}
+
+// ClusterCacheProvider_ClusterName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClusterName'
+type ClusterCacheProvider_ClusterName_Call struct {
+	*mock.Call
+}
+
+// ClusterName is a helper method to define mock.On call
+//   - path string
+func (_e *ClusterCacheProvider_Expecter) ClusterName(path interface{}) *ClusterCacheProvider_ClusterName_Call {
+	return &ClusterCacheProvider_ClusterName_Call{Call: _e.mock.On("ClusterName", path)}
+}
+
+func (_c *ClusterCacheProvider_ClusterName_Call) Run(run func(path string)) *ClusterCacheProvider_ClusterName_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *ClusterCacheProvider_ClusterName_Call) Return(clusterName multicluster.ClusterName, b bool) *ClusterCacheProvider_ClusterName_Call {
+	_c.Call.Return(clusterName, b)
+	return _c
+}
+
+func (_c *ClusterCacheProvider_ClusterName_Call) RunAndReturn(run func(path string) (multicluster.ClusterName, bool)) *ClusterCacheProvider_ClusterName_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Engage provides a mock function for the type ClusterCacheProvider
 func (_mock *ClusterCacheProvider) Engage(context1 context.Context, clusterName multicluster.ClusterName, cluster1 cluster.Cluster) error {
 	ret := _mock.Called(context1, clusterName, cluster1)
@@ -213,3 +273,63 @@ func (_c *ClusterCacheProvider_Start_Call) RunAndReturn(run func(context1 contex
 	_c.Call.Return(run)
 	return _c
 }
+
+// State provides a mock function for the type ClusterCacheProvider
+func (_mock *ClusterCacheProvider) State(clusterName multicluster.ClusterName) (clustercache.State, error) {
+	ret := _mock.Called(clusterName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for State")
+	}
+
+	var r0 clustercache.State
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(multicluster.ClusterName) (clustercache.State, error)); ok {
+		return returnFunc(clusterName)
+	}
+	if returnFunc, ok := ret.Get(0).(func(multicluster.ClusterName) clustercache.State); ok {
+		r0 = returnFunc(clusterName)
+	} else {
+		r0 = ret.Get(0).(clustercache.State)
+	}
+	if returnFunc, ok := ret.Get(1).(func(multicluster.ClusterName) error); ok {
+		r1 = returnFunc(clusterName)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ClusterCacheProvider_State_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'State'
+type ClusterCacheProvider_State_Call struct {
+	*mock.Call
+}
+
+// State is a helper method to define mock.On call
+//   - clusterName multicluster.ClusterName
+func (_e *ClusterCacheProvider_Expecter) State(clusterName interface{}) *ClusterCacheProvider_State_Call {
+	return &ClusterCacheProvider_State_Call{Call: _e.mock.On("State", clusterName)}
+}
+
+func (_c *ClusterCacheProvider_State_Call) Run(run func(clusterName multicluster.ClusterName)) *ClusterCacheProvider_State_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 multicluster.ClusterName
+		if args[0] != nil {
+			arg0 = args[0].(multicluster.ClusterName)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *ClusterCacheProvider_State_Call) Return(state clustercache.State, err error) *ClusterCacheProvider_State_Call {
+	_c.Call.Return(state, err)
+	return _c
+}
+
+func (_c *ClusterCacheProvider_State_Call) RunAndReturn(run func(clusterName multicluster.ClusterName) (clustercache.State, error)) *ClusterCacheProvider_State_Call {
+	_c.Call.Return(run)
+	return _c
+}
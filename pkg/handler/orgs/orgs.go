@@ -8,32 +8,132 @@ import (
 	kcpcorev1alpha "github.com/kcp-dev/sdk/apis/core/v1alpha1"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/orgsstore"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/util"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 )
 
-const rootOrgName = "tenancy_kcp_io_workspace:orgs"
+const (
+	handlerName = "orgs"
+	rootOrgName = "tenancy_kcp_io_workspace:orgs"
+)
 
 type orgsAuthorizer struct {
-	clusterKey  string
-	orgsStoreID string
-	fga         openfgav1.OpenFGAServiceClient
-	mgr         mcmanager.Manager
+	orgsStore orgsstore.Provider
+	fga       openfgav1.OpenFGAServiceClient
+	mgr       mcmanager.Manager
+	models    openfga.ModelProvider
+	// orgsCluster is the workspace whose requests are checked against the
+	// orgs store, and which holds its Store object.
+	orgsCluster multicluster.ClusterName
+
+	validateRelations bool
+	consistency       openfga.ConsistencyPolicy
+	identities        *identity.Mapper
+	// denyRelationFormat derives the deny relation checked before a
+	// relation, e.g. deny_%s. Deny relations are not checked if empty.
+	denyRelationFormat string
+	// checkContext builds the context OpenFGA conditions are evaluated against.
+	checkContext *openfga.ContextBuilder
+	// typeNaming shortens groups exceeding the relation length limit.
+	typeNaming util.TypeNaming
+
+	// checker resolves models, validates relations and checks deny
+	// relations as configured by the options above. It is built in New.
+	checker *openfga.Checker
 }
 
 var _ authorization.Handler = &orgsAuthorizer{}
 
-func New(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, clusterKey, orgsStoreID string) authorization.Handler {
-	return &orgsAuthorizer{
-		clusterKey:  clusterKey,
-		orgsStoreID: orgsStoreID,
+// Option configures optional behavior of the orgs authorizer.
+type Option func(*orgsAuthorizer)
+
+// WithAuthorizationModels configures the provider resolving the authorization
+// model ID sent along with every check.
+func WithAuthorizationModels(models openfga.ModelProvider) Option {
+	return func(o *orgsAuthorizer) {
+		o.models = models
+	}
+}
+
+// WithRelationValidation skips checks whose relation is not defined in the
+// orgs store's authorization model. It requires WithAuthorizationModels.
+func WithRelationValidation(enabled bool) Option {
+	return func(o *orgsAuthorizer) {
+		o.validateRelations = enabled
+	}
+}
+
+// WithConsistency configures the consistency preference of checks per verb.
+func WithConsistency(consistency openfga.ConsistencyPolicy) Option {
+	return func(o *orgsAuthorizer) {
+		o.consistency = consistency
+	}
+}
+
+// WithIdentityMapper configures how request subjects are mapped to OpenFGA users.
+func WithIdentityMapper(identities *identity.Mapper) Option {
+	return func(o *orgsAuthorizer) {
+		o.identities = identities
+	}
+}
+
+// WithDenyRelation checks the deny relation derived from format, e.g.
+// deny_%s, before every check and denies the request if it is satisfied.
+// Without WithRelationValidation, this is an additional check for every
+// request, even if the model does not define the deny relation.
+func WithDenyRelation(format string) Option {
+	return func(o *orgsAuthorizer) {
+		o.denyRelationFormat = format
+	}
+}
+
+// WithCheckContext passes the request attributes built by builder as
+// context to OpenFGA conditions.
+func WithCheckContext(builder *openfga.ContextBuilder) Option {
+	return func(o *orgsAuthorizer) {
+		o.checkContext = builder
+	}
+}
+
+// WithTypeNaming configures how groups exceeding the relation length limit
+// are shortened in relations.
+func WithTypeNaming(naming util.TypeNaming) Option {
+	return func(o *orgsAuthorizer) {
+		o.typeNaming = naming
+	}
+}
+
+// WithOrgsCluster configures the workspace whose requests are checked
+// against the orgs store, root:orgs by default.
+func WithOrgsCluster(name string) Option {
+	return func(o *orgsAuthorizer) {
+		o.orgsCluster = multicluster.ClusterName(name)
+	}
+}
+
+func New(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, orgsStore orgsstore.Provider, opts ...Option) authorization.Handler {
+	o := &orgsAuthorizer{
+		orgsStore:   orgsStore,
 		fga:         fga,
 		mgr:         mgr,
+		orgsCluster: "root:orgs",
+		identities:  identity.DefaultMapper(),
+		typeNaming:  util.TypeNamingTruncate,
+	}
+	for _, opt := range opts {
+		opt(o)
 	}
+	o.checker = openfga.NewChecker(handlerName, o.fga, o.models, o.validateRelations, o.denyRelationFormat)
+	return o
 }
 
 func (o *orgsAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
@@ -45,14 +145,12 @@ func (o *orgsAuthorizer) Handle(ctx context.Context, req authorization.Request)
 		return authorization.NoOpinion()
 	}
 
-	cn, ok := req.Spec.Extra[o.clusterKey]
-	if !ok || len(cn) == 0 {
-		klog.V(5).Infof("request does not contain expected Extra attribute %q, skipping", o.clusterKey)
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" {
+		klog.V(5).Info("request does not contain a known cluster, skipping")
 		return authorization.NoOpinion()
 	}
 
-	clusterName := cn[0]
-
 	if req.Spec.ResourceAttributes == nil {
 		klog.V(5).Info("request does not contain ResourceAttributes, skipping")
 		return authorization.NoOpinion()
@@ -70,21 +168,71 @@ func (o *orgsAuthorizer) Handle(ctx context.Context, req authorization.Request)
 	}
 	klog.V(2).Infof("request cluster name %q matches org workspace ID %q, requesting fga", clusterName, orgsWorkspaceID)
 
+	orgsStoreID, ok := o.orgsStore.StoreID()
+	if !ok {
+		klog.V(2).Info("orgs store ID not resolved yet, skipping")
+		return authorization.NoOpinion()
+	}
+
+	modelID, err := o.checker.ModelID(ctx, orgsStoreID, o.orgsStore.AuthorizationModelID())
+	if err != nil {
+		klog.ErrorS(err, "failed to resolve authorization model", "storeID", orgsStoreID)
+		return authorization.NoOpinion()
+	}
+
 	attrs := req.Spec.ResourceAttributes
 
-	group := util.CapGroupToRelationLength(schema.GroupVersionResource{Group: attrs.Group, Version: attrs.Version, Resource: attrs.Resource}, 50)
+	group := o.typeNaming.Group(schema.GroupVersionResource{Group: attrs.Group, Version: attrs.Version, Resource: attrs.Resource}, util.MaxRelationLength)
 	group = strings.ReplaceAll(group, ".", "_")
 
-	res, err := o.fga.Check(ctx, &openfgav1.CheckRequest{
-		StoreId: o.orgsStoreID,
+	user, subjectTuples, err := o.checker.Subject(ctx, o.identities, req.Spec, clusterName, orgsStoreID, modelID)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to map request subject, skipping", "user", req.Spec.User)
+		return authorization.NoOpinion()
+	}
+
+	check := &openfgav1.CheckRequest{
+		StoreId:              orgsStoreID,
+		AuthorizationModelId: modelID,
+		Consistency:          o.consistency.For(attrs.Verb),
 		TupleKey: &openfgav1.CheckRequestTupleKey{
 			Object:   rootOrgName,
 			Relation: fmt.Sprintf("%s_%s_%s", attrs.Verb, group, attrs.Resource),
-			User:     fmt.Sprintf("user:%s", req.Spec.User),
+			User:     user,
 		},
-	})
+	}
+
+	if subjectTuples != nil {
+		check.ContextualTuples = &openfgav1.ContextualTupleKeys{
+			TupleKeys: subjectTuples,
+		}
+	}
+
+	check.Context, err = o.checkContext.Build(req.Spec, clusterName, "")
+	if err != nil {
+		klog.ErrorS(err, "failed to build check context")
+		return authorization.NoOpinion()
+	}
+
+	if res, ok := o.checker.Denied(ctx, check); ok {
+		return res
+	}
+
+	mapped, err := o.checker.IsMapped(ctx, check)
+	if err != nil {
+		klog.ErrorS(err, "failed to load authorization model", "storeID", orgsStoreID, "modelID", modelID)
+		return authorization.NoOpinion()
+	}
+	if !mapped {
+		klog.V(2).InfoS("relation not defined in authorization model, skipping check", "relation", check.TupleKey.Relation, "modelID", modelID)
+		metrics.RecordUnmapped(handlerName)
+		return authorization.NoOpinion()
+	}
+
+	res, err := o.fga.Check(ctx, check)
+	metrics.RecordCheck(handlerName, res.GetAllowed(), err)
 	if err != nil {
-		klog.Errorf("error checking fga for user %q in orgs store %q: %v", req.Spec.User, o.orgsStoreID, err)
+		klog.Errorf("error checking fga for user %q in orgs store %q: %v", req.Spec.User, orgsStoreID, err)
 		return authorization.NoOpinion()
 	}
 
@@ -96,7 +244,7 @@ func (o *orgsAuthorizer) Handle(ctx context.Context, req authorization.Request)
 }
 
 func (o *orgsAuthorizer) getOrgsWorkspaceID(ctx context.Context) (string, error) {
-	orgsCluster, err := o.mgr.GetCluster(ctx, "root:orgs")
+	orgsCluster, err := o.mgr.GetCluster(ctx, o.orgsCluster)
 	if err != nil {
 		return "", err
 	}
@@ -8,8 +8,11 @@ import (
 	kcpcorev1alpha "github.com/kcp-dev/sdk/apis/core/v1alpha1"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
 	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/orgs"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
@@ -20,12 +23,25 @@ import (
 )
 
 func TestHandler(t *testing.T) {
+	consistency, err := openfga.ParseConsistencyPolicy(map[string]string{"create": "HIGHER_CONSISTENCY", "*": "MINIMIZE_LATENCY"})
+	assert.NoError(t, err)
+
+	identities, err := identity.NewMapper(identity.MapperConfig{
+		Source:             identity.SourceUsername,
+		PrefixReplacements: map[string]string{"oidc:": ""},
+		Lowercase:          true,
+	})
+	assert.NoError(t, err)
+
 	testCases := []struct {
 		name              string
 		req               authorization.Request
 		res               authorization.Response
 		fgaMocks          func(openfga *mocks.OpenFGAServiceClient)
 		setupManagerMocks func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client)
+		orgsStoreMocks    func(orgsStore *mocks.OrgsStoreProvider)
+		modelMocks        func(models *mocks.ModelProvider)
+		opts              []orgs.Option
 	}{
 		{
 			name: "should skip processing if no extra attrs present",
@@ -149,11 +165,47 @@ func TestHandler(t *testing.T) {
 					Return(nil)
 			},
 		},
+		{
+			name: "should read the workspace ID from the configured orgs cluster",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithOrgsCluster("root:tenants")},
+			setupManagerMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:tenants")).Return(cluster, nil)
+				cluster.EXPECT().GetClient().Return(orgsClient)
+				orgsClient.EXPECT().
+					Get(mock.Anything, types.NamespacedName{Name: "cluster"}, mock.Anything).
+					Run(func(ctx context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) {
+						lc := obj.(*kcpcorev1alpha.LogicalCluster)
+						lc.Annotations = map[string]string{"kcp.io/cluster": "a"}
+					}).
+					Return(nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
 		{
 			name: "should allow if fga check allows",
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -178,6 +230,7 @@ func TestHandler(t *testing.T) {
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -197,11 +250,466 @@ func TestHandler(t *testing.T) {
 					}, nil)
 			},
 		},
+		{
+			name: "should skip processing if orgs store is not resolved",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.NoOpinion(),
+			orgsStoreMocks: func(orgsStore *mocks.OrgsStoreProvider) {
+				orgsStore.EXPECT().StoreID().Return("", false)
+			},
+		},
+		{
+			name: "should check against the current orgs store",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			orgsStoreMocks: func(orgsStore *mocks.OrgsStoreProvider) {
+				orgsStore.EXPECT().StoreID().Return("rotated", true)
+				orgsStore.EXPECT().AuthorizationModelID().Return("")
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.StoreId == "rotated"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check with the resolved authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.AuthorizationModelId == "model-id"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check with the authorization model pinned on the Store",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			orgsStoreMocks: func(orgsStore *mocks.OrgsStoreProvider) {
+				orgsStore.EXPECT().StoreID().Return("b", true)
+				orgsStore.EXPECT().AuthorizationModelID().Return("pinned-model-id")
+			},
+			modelMocks: func(models *mocks.ModelProvider) {},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.AuthorizationModelId == "pinned-model-id"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should skip processing if authorization model cannot be resolved",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.NoOpinion(),
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("", errors.New("unavailable"))
+			},
+		},
+		{
+			name: "should check relation defined in the authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithRelationValidation(true)},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "b", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{
+							Type:      "tenancy_kcp_io_workspace",
+							Relations: map[string]*openfgav1.Userset{"get_a_c": {}},
+						},
+					},
+				}), nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should skip check for relation not defined in the authorization model",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "delete",
+						},
+					},
+				},
+			},
+			res:  authorization.NoOpinion(),
+			opts: []orgs.Option{orgs.WithRelationValidation(true)},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "b", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{
+							Type:      "tenancy_kcp_io_workspace",
+							Relations: map[string]*openfgav1.Userset{"get_a_c": {}},
+						},
+					},
+				}), nil)
+			},
+		},
+		{
+			name: "should skip processing if the authorization model cannot be loaded",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.NoOpinion(),
+			opts: []orgs.Option{orgs.WithRelationValidation(true)},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "b", "model-id").Return(nil, errors.New("unavailable"))
+			},
+		},
+		{
+			name: "should check with the consistency preference of the verb",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithConsistency(consistency)},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.Consistency == openfgav1.ConsistencyPreference_MINIMIZE_LATENCY
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check service accounts with their own type",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "system:serviceaccount:default:builder",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.User == "core_serviceaccount:a/default/builder" &&
+						len(in.ContextualTuples.GetTupleKeys()) == 1 &&
+						in.ContextualTuples.TupleKeys[0].User == "core_namespace:a/default"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check service accounts with their own type if the model defines it",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "system:serviceaccount:default:builder",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithRelationValidation(true)},
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "b", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{Type: "tenancy_kcp_io_workspace", Relations: map[string]*openfgav1.Userset{"get_a_c": {}}},
+						{Type: "core_serviceaccount", Relations: map[string]*openfgav1.Userset{"parent": {}}},
+						{Type: "core_namespace"},
+					},
+				}), nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.User == "core_serviceaccount:a/default/builder" &&
+						len(in.ContextualTuples.GetTupleKeys()) == 1
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check service accounts as users if the model does not define them, without relation validation",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "system:serviceaccount:default:builder",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res: authorization.Allowed(),
+			modelMocks: func(models *mocks.ModelProvider) {
+				models.EXPECT().ModelID(mock.Anything, "b").Return("model-id", nil)
+				models.EXPECT().Model(mock.Anything, "b", "model-id").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+					Id: "model-id",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{Type: "user"},
+						{Type: "tenancy_kcp_io_workspace", Relations: map[string]*openfgav1.Userset{"get_a_c": {}}},
+					},
+				}), nil)
+			},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.User == "user:system:serviceaccount:default:builder" &&
+						in.ContextualTuples == nil
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check with the user mapped by the identity mapper",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "oidc:Alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithIdentityMapper(identities)},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.User == "user:alice"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should deny if the deny relation is satisfied",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.DeniedWithReason("denied by relation deny_get_a_c on tenancy_kcp_io_workspace:orgs"),
+			opts: []orgs.Option{orgs.WithDenyRelation("deny_%s")},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "deny_get_a_c"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should check the relation if the deny relation is not satisfied",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithDenyRelation("deny_%s")},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "deny_get_a_c"
+				})).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					return in.TupleKey.Relation == "get_a_c"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
+		{
+			name: "should pass request attributes as check context",
+			req: authorization.Request{
+				SubjectAccessReview: v1.SubjectAccessReview{
+					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
+						Extra: map[string]v1.ExtraValue{
+							"authorization.kubernetes.io/cluster-name": {"a"},
+						},
+						ResourceAttributes: &v1.ResourceAttributes{
+							Group:    "a",
+							Version:  "b",
+							Resource: "c",
+							Verb:     "get",
+						},
+					},
+				},
+			},
+			res:  authorization.Allowed(),
+			opts: []orgs.Option{orgs.WithCheckContext(openfga.NewContextBuilder())},
+			fgaMocks: func(openfga *mocks.OpenFGAServiceClient) {
+				openfga.EXPECT().Check(mock.Anything, mock.MatchedBy(func(in *openfgav1.CheckRequest) bool {
+					values := in.Context.AsMap()
+					return values["verb"] == "get" && values["cluster_name"] == "a" && values["resource"] == "c"
+				})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+		},
 		{
 			name: "should skip processing if fga check returns an error",
 			req: authorization.Request{
 				SubjectAccessReview: v1.SubjectAccessReview{
 					Spec: v1.SubjectAccessReviewSpec{
+						User: "alice",
 						Extra: map[string]v1.ExtraValue{
 							"authorization.kubernetes.io/cluster-name": {"a"},
 						},
@@ -246,11 +754,29 @@ func TestHandler(t *testing.T) {
 				test.fgaMocks(openfga)
 			}
 
-			h := orgs.New(openfga, mgr, "authorization.kubernetes.io/cluster-name", "b")
+			orgsStore := mocks.NewOrgsStoreProvider(t)
+			if test.orgsStoreMocks != nil {
+				test.orgsStoreMocks(orgsStore)
+			} else {
+				orgsStore.EXPECT().StoreID().Return("b", true).Maybe()
+				orgsStore.EXPECT().AuthorizationModelID().Return("").Maybe()
+			}
+
+			opts := test.opts
+			if test.modelMocks != nil {
+				models := mocks.NewModelProvider(t)
+				test.modelMocks(models)
+				opts = append(opts, orgs.WithAuthorizationModels(models))
+			}
+
+			h := orgs.New(openfga, mgr, orgsStore, opts...)
 
 			ctx := t.Context()
 
-			res := h.Handle(ctx, test.req)
+			req, err := authorization.NewRequest(test.req.SubjectAccessReview, clustercache.NewResolver(nil, "authorization.kubernetes.io/cluster-name"))
+			assert.NoError(t, err)
+
+			res := h.Handle(ctx, req)
 			assert.Equal(t, test.res, res)
 
 		})
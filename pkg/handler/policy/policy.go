@@ -0,0 +1,260 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/retry"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+	"sigs.k8s.io/yaml"
+)
+
+// costLimit bounds the runtime cost of evaluating a single rule, like the
+// per expression limit of Kubernetes.
+const costLimit = 1000000
+
+// Effect is the response of a rule whose expression evaluates to true.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// Rule is a CEL expression over the request deciding it with Effect.
+type Rule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Effect     Effect `json:"effect"`
+}
+
+// Config is the format of the policy file.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Load reads the rules of a policy file. It returns no rules if path is empty.
+func Load(path string) ([]Rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	return cfg.Rules, nil
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+type policyAuthorizer struct {
+	clusterCache clustercache.Provider
+	rules        []compiledRule
+	// usesCluster is true if any rule references the cluster variable, which
+	// requires the cluster to be cached.
+	usesCluster bool
+
+	cacheMissTracker    retry.Tracker[string]
+	cacheMissRetryAfter time.Duration
+}
+
+var _ authorization.Handler = &policyAuthorizer{}
+
+// Option configures optional behavior of the policy authorizer.
+type Option func(*policyAuthorizer)
+
+// WithCacheMissRetry retries requests for clusters which are not yet engaged
+// after retryAfter, as long as tracker allows it.
+func WithCacheMissRetry(tracker retry.Tracker[string], retryAfter time.Duration) Option {
+	return func(p *policyAuthorizer) {
+		p.cacheMissTracker = tracker
+		p.cacheMissRetryAfter = retryAfter
+	}
+}
+
+// New returns a handler deciding requests by the first rule whose expression
+// evaluates to true. Expressions can use the variables
+//
+//	user, uid            string
+//	groups               list(string)
+//	extra                map(string, list(string))
+//	resource             map(string, string) with verb, group, version,
+//	                     resource, subresource, namespace and name
+//	nonResource          map(string, string) with verb and path
+//	cluster              map(string, string) with name, accountName,
+//	                     parentClusterID and storeID
+//
+// Attributes absent from the request are empty strings. Requests for which a
+// deny rule fails to evaluate, e.g. by exceeding the cost limit, are denied.
+// If any rule uses cluster, requests for clusters which are not cached yet are
+// answered like other cache misses instead of evaluating the rules with empty
+// cluster values. Clusters outside of organizations are never cached, their
+// values except name are empty.
+func New(clusterCache clustercache.Provider, rules []Rule, opts ...Option) (authorization.Handler, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("user", cel.StringType),
+		cel.Variable("uid", cel.StringType),
+		cel.Variable("groups", cel.ListType(cel.StringType)),
+		cel.Variable("extra", cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("nonResource", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("cluster", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &policyAuthorizer{
+		clusterCache: clusterCache,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for _, rule := range rules {
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return nil, fmt.Errorf("rule %q: unknown effect %q, expected %q or %q", rule.Name, rule.Effect, EffectAllow, EffectDeny)
+		}
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("rule %q: expression must evaluate to bool, got %s", rule.Name, ast.OutputType())
+		}
+
+		program, err := env.Program(ast, cel.CostLimit(costLimit))
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		p.rules = append(p.rules, compiledRule{Rule: rule, program: program})
+
+		for _, ref := range ast.NativeRep().ReferenceMap() {
+			if ref.Name == "cluster" {
+				p.usesCluster = true
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *policyAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
+	klog.V(5).Info("handling request in PolicyAuthorizer")
+
+	if len(p.rules) == 0 {
+		return authorization.NoOpinion()
+	}
+
+	activation, ok := p.activation(req)
+	if !ok {
+		return p.cacheMiss(req)
+	}
+	for _, rule := range p.rules {
+		out, _, err := rule.program.ContextEval(ctx, activation)
+		if err != nil && rule.Effect == EffectDeny {
+			klog.ErrorS(err, "failed to evaluate policy deny rule, denying", "rule", rule.Name, "user", req.Spec.User)
+			return authorization.DeniedWithReason(fmt.Sprintf("policy rule %s failed to evaluate", rule.Name))
+		}
+		if err != nil {
+			klog.ErrorS(err, "failed to evaluate policy rule, skipping", "rule", rule.Name)
+			continue
+		}
+		if matched, ok := out.Value().(bool); !ok || !matched {
+			continue
+		}
+
+		klog.V(2).InfoS("policy rule matched", "rule", rule.Name, "effect", rule.Effect, "user", req.Spec.User)
+		if rule.Effect == EffectDeny {
+			return authorization.DeniedWithReason(fmt.Sprintf("denied by policy rule %s", rule.Name))
+		}
+		return authorization.Allowed()
+	}
+
+	return authorization.NoOpinion()
+}
+
+// cacheMiss answers requests whose cluster is not ready in the cluster cache.
+func (p *policyAuthorizer) cacheMiss(req authorization.Request) authorization.Response {
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" {
+		// workspace paths are resolved once their cluster is engaged
+		clusterName = req.Attributes.ClusterPath
+	}
+	return clustercache.CacheMiss(p.clusterCache, p.cacheMissTracker, p.cacheMissRetryAfter, clusterName)
+}
+
+// activation returns the variables rules are evaluated against. It returns
+// false if the rules use the cluster of the request, which is not cached.
+func (p *policyAuthorizer) activation(req authorization.Request) (map[string]any, bool) {
+	extra := make(map[string][]string, len(req.Spec.Extra))
+	for key, values := range req.Spec.Extra {
+		extra[key] = values
+	}
+
+	resource := map[string]string{
+		"verb": "", "group": "", "version": "", "resource": "",
+		"subresource": "", "namespace": "", "name": "",
+	}
+	if attrs := req.Spec.ResourceAttributes; attrs != nil {
+		resource["verb"] = attrs.Verb
+		resource["group"] = attrs.Group
+		resource["version"] = attrs.Version
+		resource["resource"] = attrs.Resource
+		resource["subresource"] = attrs.Subresource
+		resource["namespace"] = attrs.Namespace
+		resource["name"] = attrs.Name
+	}
+
+	nonResource := map[string]string{"verb": "", "path": ""}
+	if attrs := req.Spec.NonResourceAttributes; attrs != nil {
+		nonResource["verb"] = attrs.Verb
+		nonResource["path"] = attrs.Path
+	}
+
+	cluster := map[string]string{"name": "", "accountName": "", "parentClusterID": "", "storeID": ""}
+	if clusterName := req.Attributes.ClusterName; clusterName != "" {
+		cluster["name"] = clusterName
+		if info, ok := p.clusterCache.Get(multicluster.ClusterName(clusterName)); ok {
+			cluster["accountName"] = info.AccountName
+			cluster["parentClusterID"] = info.ParentClusterID
+			cluster["storeID"] = info.StoreID
+		} else if p.usesCluster {
+			if state, _ := p.clusterCache.State(multicluster.ClusterName(clusterName)); state != clustercache.StateIgnored {
+				return nil, false
+			}
+		}
+	} else if req.Attributes.ClusterPath != "" && p.usesCluster {
+		return nil, false
+	}
+
+	groups := req.Spec.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return map[string]any{
+		"user":        req.Spec.User,
+		"uid":         req.Spec.UID,
+		"groups":      groups,
+		"extra":       extra,
+		"resource":    resource,
+		"nonResource": nonResource,
+		"cluster":     cluster,
+	}, true
+}
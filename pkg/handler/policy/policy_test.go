@@ -0,0 +1,310 @@
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	v1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+const clusterKey = "authorization.kubernetes.io/cluster-name"
+
+var rules = []policy.Rule{
+	{
+		Name:       "protect-kube-namespaces",
+		Expression: `resource.verb == "delete" && resource.resource == "namespaces" && resource.name.startsWith("kube-")`,
+		Effect:     policy.EffectDeny,
+	},
+	{
+		Name:       "kcp-system",
+		Expression: `groups.exists(g, g.startsWith("system:kcp:"))`,
+		Effect:     policy.EffectAllow,
+	},
+	{
+		Name:       "account-admins",
+		Expression: `cluster.accountName == "admins" && user.startsWith("admin-")`,
+		Effect:     policy.EffectAllow,
+	},
+	{
+		Name:       "metrics",
+		Expression: `nonResource.path == "/metrics" && "oidc:scope" in extra && "metrics" in extra["oidc:scope"]`,
+		Effect:     policy.EffectAllow,
+	},
+}
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec v1.SubjectAccessReviewSpec
+		res  authorization.Response
+	}{
+		{
+			name: "should deny if a deny rule matches",
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "alice",
+				Groups:             []string{"system:kcp:admin"},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "delete", Resource: "namespaces", Name: "kube-system"},
+			},
+			res: authorization.DeniedWithReason("denied by policy rule protect-kube-namespaces"),
+		},
+		{
+			name: "should allow if an allow rule matches",
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "alice",
+				Groups:             []string{"system:authenticated", "system:kcp:admin"},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "delete", Resource: "namespaces", Name: "default"},
+			},
+			res: authorization.Allowed(),
+		},
+		{
+			name: "should evaluate cluster info",
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "admin-bob",
+				Extra:              map[string]v1.ExtraValue{clusterKey: {"a"}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "pods"},
+			},
+			res: authorization.Allowed(),
+		},
+		{
+			name: "should evaluate non-resource attributes and extras",
+			spec: v1.SubjectAccessReviewSpec{
+				User:                  "carol",
+				Extra:                 map[string]v1.ExtraValue{"oidc:scope": {"openid", "metrics"}},
+				NonResourceAttributes: &v1.NonResourceAttributes{Verb: "get", Path: "/metrics"},
+			},
+			res: authorization.Allowed(),
+		},
+		{
+			name: "should return no opinion if no rule matches",
+			spec: v1.SubjectAccessReviewSpec{
+				User:               "alice",
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "pods"},
+			},
+			res: authorization.NoOpinion(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := mocks.NewClusterCacheProvider(t)
+			cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{AccountName: "admins"}, true).Maybe()
+
+			h, err := policy.New(cc, rules)
+			assert.NoError(t, err)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{Spec: tc.spec}, clustercache.NewResolver(nil, clusterKey))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
+
+func TestHandler_CacheMiss(t *testing.T) {
+	clusterRule := policy.Rule{Name: "no-account", Expression: `cluster.name == "a" && cluster.accountName == ""`, Effect: policy.EffectDeny}
+	userRule := policy.Rule{Name: "alice", Expression: `user == "alice"`, Effect: policy.EffectAllow}
+
+	testCases := []struct {
+		name                  string
+		rules                 []policy.Rule
+		cluster               string
+		res                   authorization.Response
+		clusterCacheMocks     func(cc *mocks.ClusterCacheProvider)
+		cacheMissTrackerMocks func(tracker *mocks.Tracker[string])
+	}{
+		{
+			name:    "should retry if a rule uses the cluster and it is not cached",
+			rules:   []policy.Rule{userRule, clusterRule},
+			cluster: "a",
+			res:     authorization.Retry(time.Second),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateEngaging, nil)
+			},
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
+				tracker.EXPECT().ShouldRetry("a").Return(true)
+				tracker.EXPECT().Retried("a")
+			},
+		},
+		{
+			name:    "should return no opinion if a rule uses the cluster and retries are exhausted",
+			rules:   []policy.Rule{clusterRule},
+			cluster: "a",
+			res:     authorization.NoOpinion(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateEngaging, nil)
+			},
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
+				tracker.EXPECT().ShouldRetry("a").Return(false)
+			},
+		},
+		{
+			name:    "should retry workspace paths which are not resolved yet",
+			rules:   []policy.Rule{clusterRule},
+			cluster: "root:orgs:acme",
+			res:     authorization.Retry(time.Second),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().State(multicluster.ClusterName("root:orgs:acme")).Return(clustercache.StateUnknown, nil)
+			},
+			cacheMissTrackerMocks: func(tracker *mocks.Tracker[string]) {
+				tracker.EXPECT().ShouldRetry("root:orgs:acme").Return(true)
+				tracker.EXPECT().Retried("root:orgs:acme")
+			},
+		},
+		{
+			name:    "should evaluate empty cluster values of clusters outside of organizations",
+			rules:   []policy.Rule{clusterRule},
+			cluster: "a",
+			res:     authorization.DeniedWithReason("denied by policy rule no-account"),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+				cc.EXPECT().State(multicluster.ClusterName("a")).Return(clustercache.StateIgnored, nil)
+			},
+		},
+		{
+			name:    "should evaluate rules not using the cluster without cached cluster",
+			rules:   []policy.Rule{userRule},
+			cluster: "a",
+			res:     authorization.Allowed(),
+			clusterCacheMocks: func(cc *mocks.ClusterCacheProvider) {
+				cc.EXPECT().Get(multicluster.ClusterName("a")).Return(clustercache.ClusterInfo{}, false)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := mocks.NewClusterCacheProvider(t)
+			tc.clusterCacheMocks(cc)
+
+			cacheMissTracker := mocks.NewTracker[string](t)
+			if tc.cacheMissTrackerMocks != nil {
+				tc.cacheMissTrackerMocks(cacheMissTracker)
+			} else {
+				cacheMissTracker.EXPECT().ShouldRetry(mock.Anything).Return(false).Maybe()
+			}
+
+			h, err := policy.New(cc, tc.rules, policy.WithCacheMissRetry(cacheMissTracker, time.Second))
+			assert.NoError(t, err)
+
+			spec := v1.SubjectAccessReviewSpec{
+				User:               "alice",
+				Extra:              map[string]v1.ExtraValue{clusterKey: {tc.cluster}},
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "pods"},
+			}
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{Spec: spec}, clustercache.NewResolver(nil, clusterKey))
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.res, h.Handle(t.Context(), req))
+		})
+	}
+}
+
+func TestHandler_EvaluationErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule policy.Rule
+		res  authorization.Response
+	}{
+		{
+			name: "should deny if a deny rule fails to evaluate",
+			rule: policy.Rule{Name: "scopes", Expression: `!("metrics" in extra["oidc:scope"])`, Effect: policy.EffectDeny},
+			res:  authorization.DeniedWithReason("policy rule scopes failed to evaluate"),
+		},
+		{
+			name: "should deny if a deny rule exceeds the cost limit",
+			rule: policy.Rule{Name: "expensive", Expression: strings.Repeat("[1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(x, ", 6) + `user != ""` + strings.Repeat(")", 6), Effect: policy.EffectDeny},
+			res:  authorization.DeniedWithReason("policy rule expensive failed to evaluate"),
+		},
+		{
+			name: "should skip allow rules failing to evaluate",
+			rule: policy.Rule{Name: "scopes", Expression: `"metrics" in extra["oidc:scope"]`, Effect: policy.EffectAllow},
+			res:  authorization.NoOpinion(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := policy.New(mocks.NewClusterCacheProvider(t), []policy.Rule{tc.rule})
+			assert.NoError(t, err)
+
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{User: "alice"}}, clustercache.NewResolver(nil, clusterKey))
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.res, h.Handle(t.Context(), req))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule policy.Rule
+	}{
+		{
+			name: "should reject invalid expressions",
+			rule: policy.Rule{Name: "invalid", Expression: `user ==`, Effect: policy.EffectAllow},
+		},
+		{
+			name: "should reject unknown variables",
+			rule: policy.Rule{Name: "unknown", Expression: `verb == "get"`, Effect: policy.EffectAllow},
+		},
+		{
+			name: "should reject non-bool expressions",
+			rule: policy.Rule{Name: "string", Expression: `user`, Effect: policy.EffectAllow},
+		},
+		{
+			name: "should reject unknown effects",
+			rule: policy.Rule{Name: "effect", Expression: `true`, Effect: "audit"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := policy.New(mocks.NewClusterCacheProvider(t), []policy.Rule{tc.rule})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("should return no opinion without rules", func(t *testing.T) {
+		h, err := policy.New(mocks.NewClusterCacheProvider(t), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, authorization.NoOpinion(), h.Handle(t.Context(), authorization.Request{}))
+	})
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	err := os.WriteFile(path, []byte(`
+rules:
+- name: protect-kube-namespaces
+  expression: resource.resource == "namespaces" && resource.name.startsWith("kube-")
+  effect: deny
+`), 0o600)
+	assert.NoError(t, err)
+
+	loaded, err := policy.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []policy.Rule{{
+		Name:       "protect-kube-namespaces",
+		Expression: `resource.resource == "namespaces" && resource.name.startsWith("kube-")`,
+		Effect:     policy.EffectDeny,
+	}}, loaded)
+
+	loaded, err = policy.Load("")
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+}
@@ -0,0 +1,42 @@
+package scopes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+
+	"k8s.io/klog/v2"
+)
+
+type scopeAuthorizer struct{}
+
+var _ authorization.Handler = &scopeAuthorizer{}
+
+// New returns a handler denying requests of identities whose kcp scopes do
+// not include the cluster of the request, like kcp's own authorizers do.
+func New() authorization.Handler {
+	return &scopeAuthorizer{}
+}
+
+func (s *scopeAuthorizer) Handle(ctx context.Context, req authorization.Request) authorization.Response {
+	klog.V(5).Info("handling request in ScopeAuthorizer")
+
+	if len(req.Attributes.Scopes) == 0 {
+		return authorization.NoOpinion()
+	}
+
+	clusterName := req.Attributes.ClusterName
+	if clusterName == "" {
+		klog.V(5).Info("request does not contain a known cluster, skipping")
+		return authorization.NoOpinion()
+	}
+
+	if identity.InScope(req.Attributes.Groups, req.Attributes.Scopes, clusterName) {
+		return authorization.NoOpinion()
+	}
+
+	klog.V(5).InfoS("request is outside of the scopes of its identity", "user", req.Spec.User, "clusterName", clusterName, "scopes", req.Attributes.Scopes)
+	return authorization.DeniedWithReason(fmt.Sprintf("access to cluster %q is outside of the scopes of user %q", clusterName, req.Spec.User))
+}
@@ -0,0 +1,65 @@
+package scopes_test
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/clustercache"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/scopes"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/authorization/v1"
+)
+
+const clusterKey = "authorization.kubernetes.io/cluster-name"
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		name  string
+		extra map[string]v1.ExtraValue
+		res   authorization.Response
+	}{
+		{
+			name:  "should return no opinion for unscoped identities",
+			extra: map[string]v1.ExtraValue{clusterKey: {"tenant-cluster"}},
+			res:   authorization.NoOpinion(),
+		},
+		{
+			name: "should return no opinion for requests in scope",
+			extra: map[string]v1.ExtraValue{
+				clusterKey:         {"tenant-cluster"},
+				identity.ScopesKey: {"cluster:tenant-cluster"},
+			},
+			res: authorization.NoOpinion(),
+		},
+		{
+			name: "should deny requests outside of the scopes",
+			extra: map[string]v1.ExtraValue{
+				clusterKey:         {"tenant-cluster"},
+				identity.ScopesKey: {"cluster:other-cluster"},
+			},
+			res: authorization.DeniedWithReason(`access to cluster "tenant-cluster" is outside of the scopes of user "alice"`),
+		},
+		{
+			name:  "should return no opinion without cluster",
+			extra: map[string]v1.ExtraValue{identity.ScopesKey: {"cluster:other-cluster"}},
+			res:   authorization.NoOpinion(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := scopes.New()
+			req, err := authorization.NewRequest(v1.SubjectAccessReview{Spec: v1.SubjectAccessReviewSpec{
+				User:               "alice",
+				Extra:              tc.extra,
+				ResourceAttributes: &v1.ResourceAttributes{Verb: "get", Resource: "configmaps"},
+			}}, clustercache.NewResolver(nil, clusterKey))
+			assert.NoError(t, err)
+
+			res := h.Handle(t.Context(), req)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
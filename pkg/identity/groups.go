@@ -0,0 +1,82 @@
+package identity
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+const (
+	// GroupType is the OpenFGA type of groups.
+	GroupType = "group"
+	// GroupMemberRelation is the relation of users to the groups they are
+	// members of.
+	GroupMemberRelation = "member"
+)
+
+// GroupFilter selects the Kubernetes groups of a request that are sent to
+// OpenFGA as contextual group memberships. Patterns use path.Match syntax,
+// except that * and ? also match /, which is no separator in group names.
+// The zero value matches no group.
+type GroupFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewGroupFilter returns a GroupFilter matching groups that match any include
+// pattern and no exclude pattern.
+func NewGroupFilter(include, exclude []string) (GroupFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := match(pattern, ""); err != nil {
+			return GroupFilter{}, fmt.Errorf("invalid group pattern %q: %w", pattern, err)
+		}
+	}
+	return GroupFilter{include: include, exclude: exclude}, nil
+}
+
+// Match reports whether group is selected by the filter.
+func (f GroupFilter) Match(group string) bool {
+	return matchAny(f.include, group) && !matchAny(f.exclude, group)
+}
+
+// GroupTuples returns group:<name>#member@<user> tuples for every group
+// selected by filter. Groups that cannot be used as an OpenFGA object ID are
+// skipped.
+func GroupTuples(user string, groups []string, filter GroupFilter) []*openfgav1.TupleKey {
+	var tuples []*openfgav1.TupleKey
+	for _, group := range groups {
+		if group == "" || strings.ContainsAny(group, "#\t\n\r ") || !filter.Match(group) {
+			continue
+		}
+		tuples = append(tuples, &openfgav1.TupleKey{
+			Object:   GroupObject(group),
+			Relation: GroupMemberRelation,
+			User:     user,
+		})
+	}
+	return tuples
+}
+
+// GroupObject returns the OpenFGA object of a group.
+func GroupObject(group string) string {
+	return fmt.Sprintf("%s:%s", GroupType, group)
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// slash replaces / in patterns and groups, so that path.Match does not treat
+// it as separator.
+const slash = "\x00"
+
+func match(pattern, group string) (bool, error) {
+	return path.Match(strings.ReplaceAll(pattern, "/", slash), strings.ReplaceAll(group, "/", slash))
+}
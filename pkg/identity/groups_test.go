@@ -0,0 +1,71 @@
+package identity_test
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupTuples(t *testing.T) {
+	testCases := []struct {
+		name    string
+		include []string
+		exclude []string
+		groups  []string
+		result  []*openfgav1.TupleKey
+	}{
+		{
+			name:    "should map included groups to member tuples",
+			include: []string{"*"},
+			exclude: []string{"system:*"},
+			groups:  []string{"admins", "system:authenticated", "oidc:developers"},
+			result: []*openfgav1.TupleKey{
+				{Object: "group:admins", Relation: "member", User: "user:alice"},
+				{Object: "group:oidc:developers", Relation: "member", User: "user:alice"},
+			},
+		},
+		{
+			name:    "should match groups containing slashes",
+			include: []string{"*"},
+			exclude: []string{"org/secret-*"},
+			groups:  []string{"org/team", "/admins", "org/secret-team"},
+			result: []*openfgav1.TupleKey{
+				{Object: "group:org/team", Relation: "member", User: "user:alice"},
+				{Object: "group:/admins", Relation: "member", User: "user:alice"},
+			},
+		},
+		{
+			name:    "should only map groups matching an include pattern",
+			include: []string{"oidc:*"},
+			groups:  []string{"admins", "oidc:developers"},
+			result: []*openfgav1.TupleKey{
+				{Object: "group:oidc:developers", Relation: "member", User: "user:alice"},
+			},
+		},
+		{
+			name:    "should skip groups that are not valid object IDs",
+			include: []string{"*"},
+			groups:  []string{"", "platform admins", "team#a"},
+		},
+		{
+			name:   "should not map groups with the zero filter",
+			groups: []string{"admins"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := identity.NewGroupFilter(tc.include, tc.exclude)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.result, identity.GroupTuples("user:alice", tc.groups, filter))
+		})
+	}
+
+	t.Run("should reject invalid patterns", func(t *testing.T) {
+		_, err := identity.NewGroupFilter([]string{"["}, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,143 @@
+package identity
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+const (
+	// SourceUsername identifies users by their username.
+	SourceUsername = "username"
+	// SourceUID identifies users by their UID, which stays stable when an
+	// identity provider renames a user.
+	SourceUID = "uid"
+	// SourceExtraPrefix identifies users by the first value of an Extra key,
+	// e.g. extra:oidc.example.com/sub.
+	SourceExtraPrefix = "extra:"
+
+	defaultUserType = "user"
+)
+
+var typePattern = regexp.MustCompile(`^[^:#@\s]{1,254}$`)
+
+// MapperConfig configures how request subjects are mapped to OpenFGA users.
+type MapperConfig struct {
+	// Source selects the attribute identifying a user: SourceUsername,
+	// SourceUID or SourceExtraPrefix followed by an Extra key.
+	Source string
+	// PrefixReplacements replaces a username prefix, e.g. "oidc:" with "".
+	// Only the longest matching prefix is replaced. UIDs and Extra values
+	// are never rewritten.
+	PrefixReplacements map[string]string
+	// Lowercase lowercases usernames.
+	Lowercase bool
+	// Types maps username prefixes, typically one per issuer, to the OpenFGA
+	// type of their users. Users without a matching prefix get the user type.
+	Types map[string]string
+}
+
+// Mapper maps the subject of a request to an OpenFGA user. Service accounts
+// are mapped to their core_serviceaccount object; openfga.Checker falls back
+// to User for models not defining it.
+type Mapper struct {
+	cfg MapperConfig
+}
+
+// DefaultMapper returns a Mapper checking users as user:<username>.
+func DefaultMapper() *Mapper {
+	return &Mapper{cfg: MapperConfig{Source: SourceUsername}}
+}
+
+// NewMapper validates cfg and returns a Mapper using it.
+func NewMapper(cfg MapperConfig) (*Mapper, error) {
+	switch {
+	case cfg.Source == "":
+		cfg.Source = SourceUsername
+	case cfg.Source == SourceUsername, cfg.Source == SourceUID:
+	case strings.HasPrefix(cfg.Source, SourceExtraPrefix) && len(cfg.Source) > len(SourceExtraPrefix):
+	default:
+		return nil, fmt.Errorf("unknown identity source %q", cfg.Source)
+	}
+
+	for prefix, fgaType := range cfg.Types {
+		if !typePattern.MatchString(fgaType) {
+			return nil, fmt.Errorf("invalid OpenFGA type %q for prefix %q", fgaType, prefix)
+		}
+	}
+
+	return &Mapper{cfg: cfg}, nil
+}
+
+// Subject returns the OpenFGA user checked for the subject of a request
+// against clusterName, together with contextual tuples describing it.
+func (m *Mapper) Subject(spec authzv1.SubjectAccessReviewSpec, clusterName string) (string, []*openfgav1.TupleKey, error) {
+	if sa, ok := ParseServiceAccount(spec, clusterName); ok {
+		return sa.Object(), sa.Tuples(), nil
+	}
+
+	if m.cfg.Source == SourceUsername {
+		return m.username(spec.User)
+	}
+
+	// UIDs and Extra values are opaque and used as they are
+	id := m.sourceID(spec)
+	if id == "" {
+		return "", nil, fmt.Errorf("request subject has no %s", m.cfg.Source)
+	}
+	return fmt.Sprintf("%s:%s", m.userType(spec.User), id), nil, nil
+}
+
+// User maps a username, like the target of an impersonation, to its OpenFGA
+// user. Usernames are always used as ID, independent of the configured source.
+func (m *Mapper) User(username string) (string, error) {
+	user, _, err := m.username(username)
+	return user, err
+}
+
+// username maps a username, applying prefix replacements and lowercasing.
+func (m *Mapper) username(username string) (string, []*openfgav1.TupleKey, error) {
+	id := username
+	if prefix, ok := longestPrefix(m.cfg.PrefixReplacements, id); ok {
+		id = m.cfg.PrefixReplacements[prefix] + strings.TrimPrefix(id, prefix)
+	}
+	if m.cfg.Lowercase {
+		id = strings.ToLower(id)
+	}
+	if id == "" {
+		return "", nil, fmt.Errorf("username %q maps to an empty ID", username)
+	}
+	return fmt.Sprintf("%s:%s", m.userType(username), id), nil, nil
+}
+
+func (m *Mapper) sourceID(spec authzv1.SubjectAccessReviewSpec) string {
+	if m.cfg.Source == SourceUID {
+		return spec.UID
+	}
+	if values := spec.Extra[strings.TrimPrefix(m.cfg.Source, SourceExtraPrefix)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// userType returns the OpenFGA type of the users with username's prefix.
+func (m *Mapper) userType(username string) string {
+	if prefix, ok := longestPrefix(m.cfg.Types, username); ok {
+		return m.cfg.Types[prefix]
+	}
+	return defaultUserType
+}
+
+func longestPrefix(prefixes map[string]string, s string) (string, bool) {
+	longest, found := "", false
+	for prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) && (!found || len(prefix) > len(longest)) {
+			longest, found = prefix, true
+		}
+	}
+	return longest, found
+}
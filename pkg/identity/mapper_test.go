@@ -0,0 +1,188 @@
+package identity_test
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/stretchr/testify/assert"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+func TestMapper_Subject(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         identity.MapperConfig
+		spec        authzv1.SubjectAccessReviewSpec
+		user        string
+		tuples      []*openfgav1.TupleKey
+		expectError bool
+	}{
+		{
+			name: "should map users to the user type",
+			spec: authzv1.SubjectAccessReviewSpec{User: "alice"},
+			user: "user:alice",
+		},
+		{
+			name: "should map service accounts to the serviceaccount type in the request cluster",
+			spec: authzv1.SubjectAccessReviewSpec{User: "system:serviceaccount:default:builder"},
+			user: "core_serviceaccount:a/default/builder",
+			tuples: []*openfgav1.TupleKey{
+				{Object: "core_serviceaccount:a/default/builder", Relation: "parent", User: "core_namespace:a/default"},
+			},
+		},
+		{
+			name: "should map service accounts to the cluster they belong to",
+			spec: authzv1.SubjectAccessReviewSpec{
+				User: "system:serviceaccount:default:builder",
+				Extra: map[string]authzv1.ExtraValue{
+					identity.ServiceAccountClusterKey: {"b"},
+				},
+			},
+			user: "core_serviceaccount:b/default/builder",
+			tuples: []*openfgav1.TupleKey{
+				{Object: "core_serviceaccount:b/default/builder", Relation: "parent", User: "core_namespace:b/default"},
+			},
+		},
+		{
+			name: "should treat malformed service account names as users",
+			spec: authzv1.SubjectAccessReviewSpec{User: "system:serviceaccount:default"},
+			user: "user:system:serviceaccount:default",
+		},
+		{
+			name: "should strip and lowercase username prefixes",
+			cfg: identity.MapperConfig{
+				PrefixReplacements: map[string]string{"oidc:": ""},
+				Lowercase:          true,
+			},
+			spec: authzv1.SubjectAccessReviewSpec{User: "oidc:Alice@Example.com"},
+			user: "user:alice@example.com",
+		},
+		{
+			name: "should replace the longest matching prefix",
+			cfg: identity.MapperConfig{
+				PrefixReplacements: map[string]string{"oidc:": "", "oidc:corp:": "corp/"},
+			},
+			spec: authzv1.SubjectAccessReviewSpec{User: "oidc:corp:alice"},
+			user: "user:corp/alice",
+		},
+		{
+			name: "should map to the type configured for the issuer prefix",
+			cfg: identity.MapperConfig{
+				PrefixReplacements: map[string]string{"partner:": ""},
+				Types:              map[string]string{"partner:": "partner_user"},
+			},
+			spec: authzv1.SubjectAccessReviewSpec{User: "partner:bob"},
+			user: "partner_user:bob",
+		},
+		{
+			name: "should identify users by UID",
+			cfg:  identity.MapperConfig{Source: identity.SourceUID},
+			spec: authzv1.SubjectAccessReviewSpec{User: "alice", UID: "1234"},
+			user: "user:1234",
+		},
+		{
+			name: "should identify users by an Extra key",
+			cfg:  identity.MapperConfig{Source: "extra:oidc.example.com/sub"},
+			spec: authzv1.SubjectAccessReviewSpec{
+				User: "alice",
+				Extra: map[string]authzv1.ExtraValue{
+					"oidc.example.com/sub": {"sub-1"},
+				},
+			},
+			user: "user:sub-1",
+		},
+		{
+			name: "should not rewrite UIDs",
+			cfg: identity.MapperConfig{
+				Source:             identity.SourceUID,
+				PrefixReplacements: map[string]string{"oidc:": ""},
+				Lowercase:          true,
+			},
+			spec: authzv1.SubjectAccessReviewSpec{User: "oidc:Alice", UID: "oidc:AbC-1"},
+			user: "user:oidc:AbC-1",
+		},
+		{
+			name: "should not rewrite Extra values",
+			cfg: identity.MapperConfig{
+				Source:    "extra:oidc.example.com/sub",
+				Lowercase: true,
+			},
+			spec: authzv1.SubjectAccessReviewSpec{
+				User: "alice",
+				Extra: map[string]authzv1.ExtraValue{
+					"oidc.example.com/sub": {"Sub-1"},
+				},
+			},
+			user: "user:Sub-1",
+		},
+		{
+			name:        "should fail if the username is empty after stripping its prefix",
+			cfg:         identity.MapperConfig{PrefixReplacements: map[string]string{"oidc:": ""}},
+			spec:        authzv1.SubjectAccessReviewSpec{User: "oidc:"},
+			expectError: true,
+		},
+		{
+			name:        "should fail if the UID is missing",
+			cfg:         identity.MapperConfig{Source: identity.SourceUID},
+			spec:        authzv1.SubjectAccessReviewSpec{User: "alice"},
+			expectError: true,
+		},
+		{
+			name:        "should fail if the Extra key is missing",
+			cfg:         identity.MapperConfig{Source: "extra:oidc.example.com/sub"},
+			spec:        authzv1.SubjectAccessReviewSpec{User: "alice"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapper, err := identity.NewMapper(tc.cfg)
+			assert.NoError(t, err)
+
+			user, tuples, err := mapper.Subject(tc.spec, "a")
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.user, user)
+			assert.Equal(t, tc.tuples, tuples)
+		})
+	}
+}
+
+func TestMapper_User(t *testing.T) {
+	mapper, err := identity.NewMapper(identity.MapperConfig{
+		Source:             identity.SourceUID,
+		PrefixReplacements: map[string]string{"oidc:": ""},
+	})
+	assert.NoError(t, err)
+
+	user, err := mapper.User("oidc:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "user:alice", user)
+
+	_, err = mapper.User("")
+	assert.Error(t, err)
+
+	_, err = mapper.User("oidc:")
+	assert.Error(t, err)
+
+	user, err = identity.DefaultMapper().User("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "user:alice", user)
+}
+
+func TestNewMapper(t *testing.T) {
+	_, err := identity.NewMapper(identity.MapperConfig{Source: "email"})
+	assert.Error(t, err)
+
+	_, err = identity.NewMapper(identity.MapperConfig{Source: identity.SourceExtraPrefix})
+	assert.Error(t, err)
+
+	_, err = identity.NewMapper(identity.MapperConfig{Types: map[string]string{"partner:": "partner:user"}})
+	assert.Error(t, err)
+}
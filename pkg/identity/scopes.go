@@ -0,0 +1,31 @@
+package identity
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// ScopesKey is the Extra key kcp sets to the scopes a token is
+	// restricted to, e.g. cluster:<name>.
+	ScopesKey = "authentication.kcp.io/scopes"
+
+	clusterScopePrefix = "cluster:"
+	privilegedGroup    = "system:masters"
+)
+
+// InScope reports whether the kcp scopes of a subject permit access to
+// clusterName, following kcp: every scopes value is a comma separated list of
+// which one has to match, and members of system:masters are never restricted.
+// Subjects without scopes are in scope everywhere.
+func InScope(groups, scopes []string, clusterName string) bool {
+	if slices.Contains(groups, privilegedGroup) {
+		return true
+	}
+	for _, scopes := range scopes {
+		if !slices.Contains(strings.Split(scopes, ","), clusterScopePrefix+clusterName) {
+			return false
+		}
+	}
+	return true
+}
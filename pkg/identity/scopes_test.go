@@ -0,0 +1,52 @@
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInScope(t *testing.T) {
+	testCases := []struct {
+		name   string
+		groups []string
+		scopes []string
+		inside bool
+	}{
+		{
+			name:   "should not restrict unscoped subjects",
+			inside: true,
+		},
+		{
+			name:   "should match a cluster scope",
+			scopes: []string{"cluster:tenant-cluster"},
+			inside: true,
+		},
+		{
+			name:   "should match any scope of a comma separated list",
+			scopes: []string{"cluster:other-cluster,cluster:tenant-cluster"},
+			inside: true,
+		},
+		{
+			name:   "should reject other clusters",
+			scopes: []string{"cluster:other-cluster"},
+		},
+		{
+			name:   "should require every scopes value to match",
+			scopes: []string{"cluster:tenant-cluster", "cluster:other-cluster"},
+		},
+		{
+			name:   "should not restrict system:masters",
+			groups: []string{"system:masters"},
+			scopes: []string{"cluster:other-cluster"},
+			inside: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.inside, identity.InScope(tc.groups, tc.scopes, "tenant-cluster"))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+const (
+	serviceAccountType = "core_serviceaccount"
+	namespaceType      = "core_namespace"
+	parentRelation     = "parent"
+
+	serviceAccountPrefix = "system:serviceaccount:"
+
+	// ServiceAccountClusterKey is the Extra key kcp sets to the logical
+	// cluster a service account belongs to.
+	ServiceAccountClusterKey = "authentication.kubernetes.io/cluster-name"
+)
+
+// ServiceAccount identifies a service account in a logical cluster.
+type ServiceAccount struct {
+	ClusterName string
+	Namespace   string
+	Name        string
+}
+
+// ParseServiceAccount returns the service account the request subject
+// authenticated as. Service accounts without cluster information are assumed
+// to live in clusterName.
+func ParseServiceAccount(spec authzv1.SubjectAccessReviewSpec, clusterName string) (ServiceAccount, bool) {
+	rest, ok := strings.CutPrefix(spec.User, serviceAccountPrefix)
+	if !ok {
+		return ServiceAccount{}, false
+	}
+
+	namespace, name, ok := strings.Cut(rest, ":")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, ":") {
+		return ServiceAccount{}, false
+	}
+
+	if cn := spec.Extra[ServiceAccountClusterKey]; len(cn) > 0 && cn[0] != "" {
+		clusterName = cn[0]
+	}
+
+	return ServiceAccount{ClusterName: clusterName, Namespace: namespace, Name: name}, true
+}
+
+// Object returns the OpenFGA object of the service account.
+func (sa ServiceAccount) Object() string {
+	return fmt.Sprintf("%s:%s/%s/%s", serviceAccountType, sa.ClusterName, sa.Namespace, sa.Name)
+}
+
+// NamespaceObject returns the OpenFGA object of the service account's namespace.
+func (sa ServiceAccount) NamespaceObject() string {
+	return fmt.Sprintf("%s:%s/%s", namespaceType, sa.ClusterName, sa.Namespace)
+}
+
+// Tuples returns the contextual tuples parenting the service account to its namespace.
+func (sa ServiceAccount) Tuples() []*openfgav1.TupleKey {
+	return []*openfgav1.TupleKey{{
+		Object:   sa.Object(),
+		Relation: parentRelation,
+		User:     sa.NamespaceObject(),
+	}}
+}
@@ -0,0 +1,321 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Attributes are the request attributes available to templates.
+type Attributes struct {
+	Verb string
+	// APIGroup is the API group of the request, Group the group shortened
+	// for use in relations, e.g. core for the core group.
+	APIGroup    string
+	Group       string
+	Version     string
+	Resource    string
+	Subresource string
+	Singular    string
+	// ObjectType is the OpenFGA type derived from the resource, e.g. apps_deployment.
+	ObjectType string
+	Namespace  string
+	Name       string
+
+	ClusterName     string
+	AccountName     string
+	ParentClusterID string
+}
+
+// Rule configures the templates of a resource. Empty fields of resource
+// rules inherit the default rule.
+type Rule struct {
+	// Group and Resource select the resource of an override.
+	Group    string `json:"group,omitempty"`
+	Resource string `json:"resource,omitempty"`
+
+	// Object is the named object checked for object verbs.
+	Object string `json:"object,omitempty"`
+	// Relation is checked on Object.
+	Relation string `json:"relation,omitempty"`
+	// SubresourceRelation is checked on Object for subresources.
+	SubresourceRelation string `json:"subresourceRelation,omitempty"`
+	// CollectionRelation is checked on the namespace or account for verbs
+	// on collections like create or list.
+	CollectionRelation string `json:"collectionRelation,omitempty"`
+	// Account and Namespace are the parents of objects.
+	Account   string `json:"account,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// ParentRelation relates objects to their parents.
+	ParentRelation string `json:"parentRelation,omitempty"`
+	// Tuples are added as contextual tuples. Tuples with an empty field
+	// after rendering are skipped.
+	Tuples []TupleRule `json:"tuples,omitempty"`
+}
+
+// TupleRule configures the templates of a contextual tuple.
+type TupleRule struct {
+	Object   string `json:"object"`
+	Relation string `json:"relation"`
+	User     string `json:"user"`
+}
+
+// Config is the format of the mapping file.
+type Config struct {
+	Default   Rule   `json:"default,omitempty"`
+	Resources []Rule `json:"resources,omitempty"`
+}
+
+// DefaultRule returns the rule of the built-in mapping.
+func DefaultRule() Rule {
+	return Rule{
+		Object:              "{{.ObjectType}}:{{.ClusterName}}/{{.Name}}",
+		Relation:            "{{.Verb}}",
+		SubresourceRelation: "{{.Verb}}_{{.Subresource}}",
+		CollectionRelation:  "{{.Verb}}_{{.Group}}_{{.Resource}}",
+		Account:             "core_platform-mesh_io_account:{{.ParentClusterID}}/{{.AccountName}}",
+		Namespace:           "core_namespace:{{.ClusterName}}/{{.Namespace}}",
+		ParentRelation:      "parent",
+	}
+}
+
+// Mapping is the result of rendering a rule for a request.
+type Mapping struct {
+	Object              string
+	Relation            string
+	SubresourceRelation string
+	CollectionRelation  string
+	Account             string
+	Namespace           string
+	ParentRelation      string
+	Tuples              []*openfgav1.TupleKey
+}
+
+type tupleTemplate struct {
+	object, relation, user *template.Template
+}
+
+type compiledRule struct {
+	object, relation, subresourceRelation, collectionRelation *template.Template
+	account, namespace, parentRelation                        *template.Template
+	tuples                                                    []tupleTemplate
+}
+
+// Mapper maps requests to OpenFGA objects, relations and contextual tuples.
+type Mapper struct {
+	defaultRule *compiledRule
+	byResource  map[schema.GroupResource]*compiledRule
+}
+
+// Default returns a mapper of the built-in mapping.
+func Default() *Mapper {
+	m, err := New(Config{})
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// New compiles cfg. The default rule of cfg overrides the built-in mapping,
+// resource rules override the default rule.
+func New(cfg Config) (*Mapper, error) {
+	defaultRule := merge(DefaultRule(), cfg.Default)
+	compiled, err := compile(defaultRule)
+	if err != nil {
+		return nil, fmt.Errorf("default rule: %w", err)
+	}
+
+	m := &Mapper{
+		defaultRule: compiled,
+		byResource:  map[schema.GroupResource]*compiledRule{},
+	}
+	for _, rule := range cfg.Resources {
+		if rule.Resource == "" {
+			return nil, fmt.Errorf("rule for group %q without resource", rule.Group)
+		}
+		gr := schema.GroupResource{Group: rule.Group, Resource: rule.Resource}
+		if _, ok := m.byResource[gr]; ok {
+			return nil, fmt.Errorf("duplicate rule for %s", gr)
+		}
+		compiled, err := compile(merge(defaultRule, rule))
+		if err != nil {
+			return nil, fmt.Errorf("rule for %s: %w", gr, err)
+		}
+		m.byResource[gr] = compiled
+	}
+	return m, nil
+}
+
+// Load reads the mapping from a YAML or JSON file. It returns the built-in
+// mapping if path is empty.
+func Load(path string) (*Mapper, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// Map renders the rule of the requested resource.
+func (m *Mapper) Map(attrs Attributes) (Mapping, error) {
+	rule := m.rule(attrs)
+
+	var mapping Mapping
+	var err error
+	for _, field := range []struct {
+		tmpl *template.Template
+		into *string
+	}{
+		{rule.object, &mapping.Object},
+		{rule.relation, &mapping.Relation},
+		{rule.subresourceRelation, &mapping.SubresourceRelation},
+		{rule.collectionRelation, &mapping.CollectionRelation},
+		{rule.account, &mapping.Account},
+		{rule.namespace, &mapping.Namespace},
+		{rule.parentRelation, &mapping.ParentRelation},
+	} {
+		if *field.into, err = render(field.tmpl, attrs); err != nil {
+			return Mapping{}, err
+		}
+	}
+
+	mapping.Tuples, err = rule.renderTuples(attrs)
+	if err != nil {
+		return Mapping{}, err
+	}
+	return mapping, nil
+}
+
+// Object renders the object of the requested resource.
+func (m *Mapper) Object(attrs Attributes) (string, error) {
+	return render(m.rule(attrs).object, attrs)
+}
+
+func (m *Mapper) rule(attrs Attributes) *compiledRule {
+	if rule, ok := m.byResource[schema.GroupResource{Group: attrs.APIGroup, Resource: attrs.Resource}]; ok {
+		return rule
+	}
+	return m.defaultRule
+}
+
+func (r *compiledRule) renderTuples(attrs Attributes) ([]*openfgav1.TupleKey, error) {
+	var tuples []*openfgav1.TupleKey
+	for _, t := range r.tuples {
+		object, err := render(t.object, attrs)
+		if err != nil {
+			return nil, err
+		}
+		relation, err := render(t.relation, attrs)
+		if err != nil {
+			return nil, err
+		}
+		user, err := render(t.user, attrs)
+		if err != nil {
+			return nil, err
+		}
+		if object == "" || relation == "" || user == "" {
+			continue
+		}
+		tuples = append(tuples, &openfgav1.TupleKey{Object: object, Relation: relation, User: user})
+	}
+	return tuples, nil
+}
+
+// merge returns base with the non-empty fields of override.
+func merge(base, override Rule) Rule {
+	for _, field := range []struct {
+		base     *string
+		override string
+	}{
+		{&base.Object, override.Object},
+		{&base.Relation, override.Relation},
+		{&base.SubresourceRelation, override.SubresourceRelation},
+		{&base.CollectionRelation, override.CollectionRelation},
+		{&base.Account, override.Account},
+		{&base.Namespace, override.Namespace},
+		{&base.ParentRelation, override.ParentRelation},
+	} {
+		if field.override != "" {
+			*field.base = field.override
+		}
+	}
+	if override.Tuples != nil {
+		base.Tuples = override.Tuples
+	}
+	return base
+}
+
+func compile(rule Rule) (*compiledRule, error) {
+	var compiled compiledRule
+	for _, field := range []struct {
+		name string
+		text string
+		into **template.Template
+	}{
+		{"object", rule.Object, &compiled.object},
+		{"relation", rule.Relation, &compiled.relation},
+		{"subresourceRelation", rule.SubresourceRelation, &compiled.subresourceRelation},
+		{"collectionRelation", rule.CollectionRelation, &compiled.collectionRelation},
+		{"account", rule.Account, &compiled.account},
+		{"namespace", rule.Namespace, &compiled.namespace},
+		{"parentRelation", rule.ParentRelation, &compiled.parentRelation},
+	} {
+		tmpl, err := parse(field.name, field.text)
+		if err != nil {
+			return nil, err
+		}
+		*field.into = tmpl
+	}
+
+	for i, t := range rule.Tuples {
+		var tuple tupleTemplate
+		var err error
+		if tuple.object, err = parse(fmt.Sprintf("tuples[%d].object", i), t.Object); err != nil {
+			return nil, err
+		}
+		if tuple.relation, err = parse(fmt.Sprintf("tuples[%d].relation", i), t.Relation); err != nil {
+			return nil, err
+		}
+		if tuple.user, err = parse(fmt.Sprintf("tuples[%d].user", i), t.User); err != nil {
+			return nil, err
+		}
+		compiled.tuples = append(compiled.tuples, tuple)
+	}
+	return &compiled, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	// catch references to unknown attributes before the first request
+	if err := tmpl.Execute(io.Discard, Attributes{}); err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, attrs Attributes) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, attrs); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
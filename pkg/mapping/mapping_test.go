@@ -0,0 +1,146 @@
+package mapping_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/mapping"
+	"github.com/stretchr/testify/assert"
+)
+
+var attrs = mapping.Attributes{
+	Verb:            "create",
+	APIGroup:        "apps",
+	Group:           "apps",
+	Version:         "v1",
+	Resource:        "deployments",
+	Singular:        "deployment",
+	ObjectType:      "apps_deployment",
+	Namespace:       "default",
+	Name:            "web",
+	ClusterName:     "a",
+	AccountName:     "origin-account",
+	ParentClusterID: "origin",
+}
+
+func TestDefault(t *testing.T) {
+	m, err := mapping.Default().Map(attrs)
+	assert.NoError(t, err)
+	assert.Equal(t, "apps_deployment:a/web", m.Object)
+	assert.Equal(t, "create", m.Relation)
+	assert.Equal(t, "create_", m.SubresourceRelation)
+	assert.Equal(t, "create_apps_deployments", m.CollectionRelation)
+	assert.Equal(t, "core_platform-mesh_io_account:origin/origin-account", m.Account)
+	assert.Equal(t, "core_namespace:a/default", m.Namespace)
+	assert.Equal(t, "parent", m.ParentRelation)
+	assert.Empty(t, m.Tuples)
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         mapping.Config
+		object      string
+		tuples      int
+		expectError bool
+	}{
+		{
+			name:   "should override the default rule",
+			cfg:    mapping.Config{Default: mapping.Rule{Object: "{{.ObjectType}}:{{.Name}}"}},
+			object: "apps_deployment:web",
+		},
+		{
+			name: "should prefer the rule of the resource",
+			cfg: mapping.Config{
+				Default:   mapping.Rule{Object: "{{.ObjectType}}:{{.Name}}"},
+				Resources: []mapping.Rule{{Group: "apps", Resource: "deployments", Object: "workload:{{.ClusterName}}/{{.Namespace}}/{{.Name}}"}},
+			},
+			object: "workload:a/default/web",
+		},
+		{
+			name: "should skip tuples rendering empty fields",
+			cfg: mapping.Config{
+				Resources: []mapping.Rule{{Group: "apps", Resource: "deployments", Tuples: []mapping.TupleRule{
+					{Object: "{{.Namespace}}", Relation: "member", User: "x"},
+					{Object: "{{.Subresource}}", Relation: "member", User: "x"},
+				}}},
+			},
+			object: "apps_deployment:a/web",
+			tuples: 1,
+		},
+		{
+			name:        "should reject invalid templates",
+			cfg:         mapping.Config{Default: mapping.Rule{Relation: "{{.Verb"}},
+			expectError: true,
+		},
+		{
+			name:        "should reject unknown attributes",
+			cfg:         mapping.Config{Default: mapping.Rule{Relation: "{{.Kind}}"}},
+			expectError: true,
+		},
+		{
+			name:        "should reject rules without resource",
+			cfg:         mapping.Config{Resources: []mapping.Rule{{Group: "apps"}}},
+			expectError: true,
+		},
+		{
+			name: "should reject duplicate rules",
+			cfg: mapping.Config{Resources: []mapping.Rule{
+				{Group: "apps", Resource: "deployments"},
+				{Group: "apps", Resource: "deployments"},
+			}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapper, err := mapping.New(tc.cfg)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			m, err := mapper.Map(attrs)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.object, m.Object)
+			assert.Len(t, m.Tuples, tc.tuples)
+
+			object, err := mapper.Object(attrs)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.object, object)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	err := os.WriteFile(path, []byte(`
+resources:
+- group: apps
+  resource: deployments
+  collectionRelation: "{{.Verb}}_workloads"
+`), 0o600)
+	assert.NoError(t, err)
+
+	mapper, err := mapping.Load(path)
+	assert.NoError(t, err)
+	m, err := mapper.Map(attrs)
+	assert.NoError(t, err)
+	assert.Equal(t, "create_workloads", m.CollectionRelation)
+
+	t.Run("should use the default mapping without file", func(t *testing.T) {
+		mapper, err := mapping.Load("")
+		assert.NoError(t, err)
+		assert.NotNil(t, mapper)
+	})
+
+	t.Run("should reject unknown fields", func(t *testing.T) {
+		err := os.WriteFile(path, []byte("resources:\n- resource: pods\n  objects: x\n"), 0o600)
+		assert.NoError(t, err)
+		_, err = mapping.Load(path)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// OutcomeAllowed is recorded when OpenFGA allowed the check.
+	OutcomeAllowed = "allowed"
+	// OutcomeNotAllowed is recorded when OpenFGA did not allow the check.
+	OutcomeNotAllowed = "not_allowed"
+	// OutcomeError is recorded when the check could not be performed.
+	OutcomeError = "error"
+	// OutcomeUnmapped is recorded when the checked type or relation does not
+	// exist in the store's authorization model and the check was skipped.
+	OutcomeUnmapped = "unmapped"
+	// OutcomeDenied is recorded when the deny relation of a check was satisfied.
+	OutcomeDenied = "denied"
+)
+
+// Checks counts OpenFGA checks by handler and outcome.
+var Checks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rebac_authz_webhook_openfga_checks_total",
+	Help: "Number of OpenFGA checks by handler and outcome.",
+}, []string{"handler", "outcome"})
+
+// Bypassed counts requests of system identities answered without OpenFGA, by action.
+var Bypassed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rebac_authz_webhook_bypassed_requests_total",
+	Help: "Number of requests of system identities answered without OpenFGA by action.",
+}, []string{"action"})
+
+// BreakGlass counts requests matching a break-glass grant by grant and decision.
+var BreakGlass = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rebac_authz_webhook_break_glass_decisions_total",
+	Help: "Number of requests matching a break-glass grant by grant and decision.",
+}, []string{"grant", "decision"})
+
+// OrgsStoreResolved is 1 once the orgs store ID has been resolved, 0 before.
+var OrgsStoreResolved = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "rebac_authz_webhook_orgs_store_resolved",
+	Help: "Whether the ID of the orgs OpenFGA store has been resolved.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(Checks, Bypassed, BreakGlass, OrgsStoreResolved)
+}
+
+// RecordCheck records the outcome of an OpenFGA check issued by handler.
+func RecordCheck(handler string, allowed bool, err error) {
+	switch {
+	case err != nil:
+		Checks.WithLabelValues(handler, OutcomeError).Inc()
+	case allowed:
+		Checks.WithLabelValues(handler, OutcomeAllowed).Inc()
+	default:
+		Checks.WithLabelValues(handler, OutcomeNotAllowed).Inc()
+	}
+}
+
+// RecordUnmapped records a check skipped by handler because its type or
+// relation is not part of the authorization model.
+func RecordUnmapped(handler string) {
+	Checks.WithLabelValues(handler, OutcomeUnmapped).Inc()
+}
+
+// RecordDenied records a request denied by handler through a deny relation.
+func RecordDenied(handler string) {
+	Checks.WithLabelValues(handler, OutcomeDenied).Inc()
+}
+
+// RecordBypass records a request answered with action without OpenFGA.
+func RecordBypass(action string) {
+	Bypassed.WithLabelValues(action).Inc()
+}
+
+// RecordBreakGlass records a decision made through a break-glass grant.
+func RecordBreakGlass(grant, decision string) {
+	BreakGlass.WithLabelValues(grant, decision).Inc()
+}
+
+// RecordOrgsStoreResolved records whether the orgs store ID is resolved.
+func RecordOrgsStoreResolved(resolved bool) {
+	if resolved {
+		OrgsStoreResolved.Set(1)
+		return
+	}
+	OrgsStoreResolved.Set(0)
+}
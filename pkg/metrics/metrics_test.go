@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordCheck(t *testing.T) {
+	metrics.Checks.Reset()
+
+	metrics.RecordCheck("test", true, nil)
+	metrics.RecordCheck("test", false, nil)
+	metrics.RecordCheck("test", false, nil)
+	metrics.RecordCheck("test", false, errors.New("unavailable"))
+	metrics.RecordUnmapped("test")
+	metrics.RecordDenied("test")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Checks.WithLabelValues("test", metrics.OutcomeAllowed)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Checks.WithLabelValues("test", metrics.OutcomeNotAllowed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Checks.WithLabelValues("test", metrics.OutcomeError)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Checks.WithLabelValues("test", metrics.OutcomeUnmapped)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Checks.WithLabelValues("test", metrics.OutcomeDenied)))
+}
+
+func TestRecordBypass(t *testing.T) {
+	metrics.Bypassed.Reset()
+
+	metrics.RecordBypass("allow")
+	metrics.RecordBypass("allow")
+	metrics.RecordBypass("noopinion")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Bypassed.WithLabelValues("allow")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Bypassed.WithLabelValues("noopinion")))
+}
+
+func TestRecordBreakGlass(t *testing.T) {
+	metrics.BreakGlass.Reset()
+
+	metrics.RecordBreakGlass("incident-42", "allowed")
+	metrics.RecordBreakGlass("incident-42", "expired")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.BreakGlass.WithLabelValues("incident-42", "allowed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.BreakGlass.WithLabelValues("incident-42", "expired")))
+}
+
+func TestRecordOrgsStoreResolved(t *testing.T) {
+	metrics.RecordOrgsStoreResolved(false)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.OrgsStoreResolved))
+
+	metrics.RecordOrgsStoreResolved(true)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrgsStoreResolved))
+}
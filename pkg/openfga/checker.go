@@ -0,0 +1,193 @@
+package openfga
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+// MaxContextualTuples is the number of contextual tuples OpenFGA accepts per
+// check by default.
+const MaxContextualTuples = 100
+
+// Checker prepares and guards the checks of a handler: it resolves the
+// authorization model checks are sent with, validates their relations against
+// that model and checks deny relations.
+type Checker struct {
+	// handler labels the metrics recorded by the checker.
+	handler            string
+	fga                openfgav1.OpenFGAServiceClient
+	models             ModelProvider
+	validateRelations  bool
+	denyRelationFormat string
+}
+
+// NewChecker returns a Checker for handler. models may be nil, in which case
+// checks are sent without model ID and relations are not validated. Deny
+// relations are not checked if denyRelationFormat is empty.
+func NewChecker(handler string, fga openfgav1.OpenFGAServiceClient, models ModelProvider, validateRelations bool, denyRelationFormat string) *Checker {
+	return &Checker{
+		handler:            handler,
+		fga:                fga,
+		models:             models,
+		validateRelations:  validateRelations,
+		denyRelationFormat: denyRelationFormat,
+	}
+}
+
+// ModelID returns pinned, the model pinned on a Store object, or the model
+// currently resolved for storeID.
+func (c *Checker) ModelID(ctx context.Context, storeID, pinned string) (string, error) {
+	if pinned != "" || c.models == nil {
+		return pinned, nil
+	}
+	return c.models.ModelID(ctx, storeID)
+}
+
+// IsMapped reports whether the object type and relation of check are defined
+// in the authorization model the check is sent with.
+func (c *Checker) IsMapped(ctx context.Context, check *openfgav1.CheckRequest) (bool, error) {
+	if !c.validates(check) {
+		return true, nil
+	}
+
+	model, err := c.models.Model(ctx, check.StoreId, check.AuthorizationModelId)
+	if err != nil {
+		return false, err
+	}
+
+	return model.HasRelation(ObjectType(check.TupleKey.Object), check.TupleKey.Relation), nil
+}
+
+// validates reports whether the relations of check are validated against its
+// authorization model.
+func (c *Checker) validates(check *openfgav1.CheckRequest) bool {
+	return c.validateRelations && c.models != nil && check.AuthorizationModelId != ""
+}
+
+// Defines reports whether the authorization model of storeID and modelID
+// defines relation on objectType. Unlike IsMapped, it consults the model even
+// without relation validation. It reports true if the model is not known.
+func (c *Checker) Defines(ctx context.Context, storeID, modelID, objectType, relation string) (bool, error) {
+	if c.models == nil || modelID == "" {
+		return true, nil
+	}
+
+	model, err := c.models.Model(ctx, storeID, modelID)
+	if err != nil {
+		return false, err
+	}
+	return model.HasRelation(objectType, relation), nil
+}
+
+// GroupTuples maps the groups selected by filter to memberships of user, if
+// the authorization model of storeID and modelID defines group members. Only
+// as many memberships are returned as fit next to the reserved contextual
+// tuples of the check.
+func (c *Checker) GroupTuples(ctx context.Context, storeID, modelID, user string, groups []string, filter identity.GroupFilter, reserved int) ([]*openfgav1.TupleKey, error) {
+	tuples := identity.GroupTuples(user, groups, filter)
+	if len(tuples) == 0 {
+		return nil, nil
+	}
+
+	defined, err := c.Defines(ctx, storeID, modelID, identity.GroupType, identity.GroupMemberRelation)
+	if err != nil {
+		return nil, err
+	}
+	if !defined {
+		klog.V(5).InfoS("authorization model does not define group members, skipping groups", "storeID", storeID, "modelID", modelID)
+		return nil, nil
+	}
+
+	if room := max(MaxContextualTuples-reserved, 0); len(tuples) > room {
+		klog.InfoS("dropping group memberships exceeding the contextual tuple limit", "user", user, "groups", len(tuples), "dropped", len(tuples)-room)
+		tuples = tuples[:room]
+	}
+	return tuples, nil
+}
+
+// Subject maps the subject of a request against clusterName with identities
+// to the OpenFGA user checked in the authorization model of storeID and
+// modelID, together with contextual tuples describing it. Models may not
+// define service accounts, which OpenFGA rejects, so they are checked as plain
+// users there. Unlike IsMapped, this does not depend on relation validation.
+func (c *Checker) Subject(ctx context.Context, identities *identity.Mapper, spec authzv1.SubjectAccessReviewSpec, clusterName, storeID, modelID string) (string, []*openfgav1.TupleKey, error) {
+	user, tuples, err := identities.Subject(spec, clusterName)
+	if err != nil || len(tuples) == 0 || c.models == nil || modelID == "" {
+		return user, tuples, err
+	}
+
+	model, err := c.models.Model(ctx, storeID, modelID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load authorization model %s: %w", modelID, err)
+	}
+	if subjectDefined(model, user, tuples) {
+		return user, tuples, nil
+	}
+
+	klog.V(5).InfoS("authorization model does not define the subject type, checking as user", "user", spec.User, "modelID", modelID)
+	user, err = identities.User(spec.User)
+	return user, nil, err
+}
+
+// subjectDefined reports whether model defines the type of user and the
+// relations of its contextual tuples.
+func subjectDefined(model *Model, user string, tuples []*openfgav1.TupleKey) bool {
+	userType, _, _ := strings.Cut(user, "#")
+	if !model.HasType(ObjectType(userType)) {
+		return false
+	}
+	for _, tuple := range tuples {
+		if !model.HasRelation(ObjectType(tuple.Object), tuple.Relation) {
+			return false
+		}
+	}
+	return true
+}
+
+// Denied checks the deny relation of check. It returns the response to send
+// if the deny relation is satisfied or cannot be checked. Without relation
+// validation, the deny relation is checked for every check and deny relations
+// rejected by OpenFGA as invalid are assumed to be undefined.
+func (c *Checker) Denied(ctx context.Context, check *openfgav1.CheckRequest) (authorization.Response, bool) {
+	if c.denyRelationFormat == "" {
+		return authorization.Response{}, false
+	}
+
+	deny := DenyCheck(check, c.denyRelationFormat)
+	if mapped, err := c.IsMapped(ctx, deny); err != nil || !mapped {
+		// the model does not deny this relation, or cannot be loaded which is
+		// handled by the check itself
+		return authorization.Response{}, false
+	}
+
+	res, err := c.fga.Check(ctx, deny)
+	if status.Code(err) == codes.InvalidArgument && !c.validates(deny) {
+		// the deny relation is probably not defined in the authorization model
+		klog.V(5).ErrorS(err, "deny relation rejected by OpenFGA, skipping", "relation", deny.TupleKey.Relation)
+		return authorization.Response{}, false
+	}
+	if err != nil {
+		metrics.RecordCheck(c.handler, false, err)
+		klog.ErrorS(err, "failed to perform OpenFGA deny check", "relation", deny.TupleKey.Relation)
+		return authorization.NoOpinion(), true
+	}
+
+	if !res.Allowed {
+		return authorization.Response{}, false
+	}
+
+	klog.InfoS("request denied by OpenFGA", "object", deny.TupleKey.Object, "relation", deny.TupleKey.Relation)
+	metrics.RecordDenied(c.handler)
+	return authorization.DeniedWithReason(fmt.Sprintf("denied by relation %s on %s", deny.TupleKey.Relation, deny.TupleKey.Object)), true
+}
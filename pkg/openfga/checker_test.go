@@ -0,0 +1,242 @@
+package openfga_test
+
+import (
+	"errors"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/authorization"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/identity"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+func TestChecker_ModelID(t *testing.T) {
+	models := mocks.NewModelProvider(t)
+	models.EXPECT().ModelID(mock.Anything, "store-id").Return("latest", nil).Once()
+
+	checker := openfga.NewChecker("test", nil, models, true, "")
+
+	id, err := checker.ModelID(t.Context(), "store-id", "pinned")
+	assert.NoError(t, err)
+	assert.Equal(t, "pinned", id)
+
+	id, err = checker.ModelID(t.Context(), "store-id", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "latest", id)
+
+	id, err = openfga.NewChecker("test", nil, nil, true, "").ModelID(t.Context(), "store-id", "")
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+}
+
+func TestChecker_IsMapped(t *testing.T) {
+	model := openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id: "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "core_namespace", Relations: map[string]*openfgav1.Userset{"get": {}}},
+		},
+	})
+	check := func(object, relation, modelID string) *openfgav1.CheckRequest {
+		return &openfgav1.CheckRequest{
+			StoreId:              "store-id",
+			AuthorizationModelId: modelID,
+			TupleKey:             &openfgav1.CheckRequestTupleKey{Object: object, Relation: relation, User: "user:alice"},
+		}
+	}
+
+	models := mocks.NewModelProvider(t)
+	models.EXPECT().Model(mock.Anything, "store-id", "model-1").Return(model, nil)
+	checker := openfga.NewChecker("test", nil, models, true, "")
+
+	mapped, err := checker.IsMapped(t.Context(), check("core_namespace:cluster/default", "get", "model-1"))
+	assert.NoError(t, err)
+	assert.True(t, mapped)
+
+	mapped, err = checker.IsMapped(t.Context(), check("core_namespace:cluster/default", "delete", "model-1"))
+	assert.NoError(t, err)
+	assert.False(t, mapped)
+
+	mapped, err = checker.IsMapped(t.Context(), check("core_pod:cluster/default/web", "get", ""))
+	assert.NoError(t, err)
+	assert.True(t, mapped, "checks without model are not validated")
+
+	mapped, err = openfga.NewChecker("test", nil, models, false, "").IsMapped(t.Context(), check("core_pod:cluster/default/web", "get", "model-1"))
+	assert.NoError(t, err)
+	assert.True(t, mapped, "relations are not validated if disabled")
+}
+
+func TestChecker_Defines(t *testing.T) {
+	models := mocks.NewModelProvider(t)
+	models.EXPECT().Model(mock.Anything, "store-id", "model-1").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id: "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "group", Relations: map[string]*openfgav1.Userset{"member": {}}},
+		},
+	}), nil)
+	models.EXPECT().Model(mock.Anything, "store-id", "broken").Return(nil, errors.New("unavailable"))
+
+	// the model is consulted without relation validation
+	checker := openfga.NewChecker("test", nil, models, false, "")
+
+	defined, err := checker.Defines(t.Context(), "store-id", "model-1", "group", "member")
+	assert.NoError(t, err)
+	assert.True(t, defined)
+
+	defined, err = checker.Defines(t.Context(), "store-id", "model-1", "group", "owner")
+	assert.NoError(t, err)
+	assert.False(t, defined)
+
+	defined, err = checker.Defines(t.Context(), "store-id", "", "group", "owner")
+	assert.NoError(t, err)
+	assert.True(t, defined, "relations of unknown models are assumed to be defined")
+
+	_, err = checker.Defines(t.Context(), "store-id", "broken", "group", "member")
+	assert.Error(t, err)
+}
+
+func TestChecker_Subject(t *testing.T) {
+	models := mocks.NewModelProvider(t)
+	models.EXPECT().Model(mock.Anything, "store-id", "with-sa").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id: "with-sa",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{Type: "core_serviceaccount", Relations: map[string]*openfgav1.Userset{"parent": {}}},
+		},
+	}), nil).Maybe()
+	models.EXPECT().Model(mock.Anything, "store-id", "without-sa").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id:              "without-sa",
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	}), nil).Maybe()
+	models.EXPECT().Model(mock.Anything, "store-id", "broken").Return(nil, errors.New("unavailable")).Maybe()
+
+	serviceAccount := authzv1.SubjectAccessReviewSpec{User: "system:serviceaccount:default:builder"}
+	parent := &openfgav1.TupleKey{Object: "core_serviceaccount:cluster/default/builder", Relation: "parent", User: "core_namespace:cluster/default"}
+
+	testCases := []struct {
+		name       string
+		spec       authzv1.SubjectAccessReviewSpec
+		modelID    string
+		wantUser   string
+		wantTuples []*openfgav1.TupleKey
+		wantErr    bool
+	}{
+		{
+			name:     "should map users without loading the model",
+			spec:     authzv1.SubjectAccessReviewSpec{User: "alice"},
+			modelID:  "broken",
+			wantUser: "user:alice",
+		},
+		{
+			name:       "should map service accounts to their type if the model defines it",
+			spec:       serviceAccount,
+			modelID:    "with-sa",
+			wantUser:   "core_serviceaccount:cluster/default/builder",
+			wantTuples: []*openfgav1.TupleKey{parent},
+		},
+		{
+			name:     "should map service accounts to users if the model does not define them",
+			spec:     serviceAccount,
+			modelID:  "without-sa",
+			wantUser: "user:system:serviceaccount:default:builder",
+		},
+		{
+			name:       "should map service accounts to their type if the model is not known",
+			spec:       serviceAccount,
+			wantUser:   "core_serviceaccount:cluster/default/builder",
+			wantTuples: []*openfgav1.TupleKey{parent},
+		},
+		{
+			name:    "should fail if the model cannot be loaded",
+			spec:    serviceAccount,
+			modelID: "broken",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the fallback does not depend on relation validation
+			checker := openfga.NewChecker("test", nil, models, false, "")
+
+			user, tuples, err := checker.Subject(t.Context(), identity.DefaultMapper(), tc.spec, "cluster", "store-id", tc.modelID)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantUser, user)
+			assert.Equal(t, tc.wantTuples, tuples)
+		})
+	}
+}
+
+func TestChecker_Denied(t *testing.T) {
+	check := &openfgav1.CheckRequest{
+		StoreId:  "store-id",
+		TupleKey: &openfgav1.CheckRequestTupleKey{Object: "core_namespace:cluster/default", Relation: "get", User: "user:alice"},
+	}
+	isDeny := mock.MatchedBy(func(in *openfgav1.CheckRequest) bool { return in.TupleKey.Relation == "deny_get" })
+
+	t.Run("should deny if the deny relation is satisfied", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().Check(mock.Anything, isDeny).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+
+		res, ok := openfga.NewChecker("test", fga, nil, true, "deny_%s").Denied(t.Context(), check)
+		assert.True(t, ok)
+		assert.Equal(t, authorization.DeniedWithReason("denied by relation deny_get on core_namespace:cluster/default"), res)
+	})
+
+	t.Run("should continue if the deny relation is not satisfied", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().Check(mock.Anything, isDeny).Return(&openfgav1.CheckResponse{}, nil)
+
+		_, ok := openfga.NewChecker("test", fga, nil, true, "deny_%s").Denied(t.Context(), check)
+		assert.False(t, ok)
+	})
+
+	t.Run("should return no opinion if the deny check fails", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().Check(mock.Anything, isDeny).Return(nil, errors.New("unavailable"))
+
+		res, ok := openfga.NewChecker("test", fga, nil, true, "deny_%s").Denied(t.Context(), check)
+		assert.True(t, ok)
+		assert.Equal(t, authorization.NoOpinion(), res)
+	})
+
+	t.Run("should skip deny relations rejected by OpenFGA without relation validation", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().Check(mock.Anything, isDeny).Return(nil, status.Error(codes.InvalidArgument, "relation 'core_namespace#deny_get' not found"))
+
+		_, ok := openfga.NewChecker("test", fga, nil, false, "deny_%s").Denied(t.Context(), check)
+		assert.False(t, ok)
+	})
+
+	t.Run("should return no opinion if a validated deny check is rejected by OpenFGA", func(t *testing.T) {
+		models := mocks.NewModelProvider(t)
+		models.EXPECT().Model(mock.Anything, "store-id", "model-1").Return(openfga.NewModel(&openfgav1.AuthorizationModel{
+			Id: "model-1",
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "core_namespace", Relations: map[string]*openfgav1.Userset{"get": {}, "deny_get": {}}},
+			},
+		}), nil)
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().Check(mock.Anything, isDeny).Return(nil, status.Error(codes.InvalidArgument, "invalid contextual tuple"))
+
+		validated := &openfgav1.CheckRequest{StoreId: "store-id", AuthorizationModelId: "model-1", TupleKey: check.TupleKey}
+		res, ok := openfga.NewChecker("test", fga, models, true, "deny_%s").Denied(t.Context(), validated)
+		assert.True(t, ok)
+		assert.Equal(t, authorization.NoOpinion(), res)
+	})
+
+	t.Run("should not check without deny relation format", func(t *testing.T) {
+		_, ok := openfga.NewChecker("test", mocks.NewOpenFGAServiceClient(t), nil, true, "").Denied(t.Context(), check)
+		assert.False(t, ok)
+	})
+}
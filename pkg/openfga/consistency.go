@@ -0,0 +1,39 @@
+package openfga
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// AnyVerb configures the consistency preference of verbs without an explicit entry.
+const AnyVerb = "*"
+
+// ConsistencyPolicy maps verbs to the consistency preference of their checks.
+// The zero value leaves the consistency to OpenFGA's default.
+type ConsistencyPolicy struct {
+	byVerb map[string]openfgav1.ConsistencyPreference
+}
+
+// ParseConsistencyPolicy parses a verb to preference mapping like
+// {"create": "HIGHER_CONSISTENCY", "*": "MINIMIZE_LATENCY"}.
+func ParseConsistencyPolicy(preferences map[string]string) (ConsistencyPolicy, error) {
+	policy := ConsistencyPolicy{byVerb: make(map[string]openfgav1.ConsistencyPreference, len(preferences))}
+	for verb, preference := range preferences {
+		value, ok := openfgav1.ConsistencyPreference_value[strings.ToUpper(preference)]
+		if !ok {
+			return ConsistencyPolicy{}, fmt.Errorf("unknown consistency preference %q for verb %q", preference, verb)
+		}
+		policy.byVerb[verb] = openfgav1.ConsistencyPreference(value)
+	}
+	return policy, nil
+}
+
+// For returns the consistency preference for checks of verb.
+func (p ConsistencyPolicy) For(verb string) openfgav1.ConsistencyPreference {
+	if preference, ok := p.byVerb[verb]; ok {
+		return preference
+	}
+	return p.byVerb[AnyVerb]
+}
@@ -0,0 +1,64 @@
+package openfga_test
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistencyPolicy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		preferences map[string]string
+		verb        string
+		result      openfgav1.ConsistencyPreference
+		expectError bool
+	}{
+		{
+			name:   "should leave the consistency unspecified by default",
+			verb:   "get",
+			result: openfgav1.ConsistencyPreference_UNSPECIFIED,
+		},
+		{
+			name:        "should use the preference of the verb",
+			preferences: map[string]string{"create": "HIGHER_CONSISTENCY", "*": "MINIMIZE_LATENCY"},
+			verb:        "create",
+			result:      openfgav1.ConsistencyPreference_HIGHER_CONSISTENCY,
+		},
+		{
+			name:        "should fall back to the wildcard preference",
+			preferences: map[string]string{"create": "HIGHER_CONSISTENCY", "*": "MINIMIZE_LATENCY"},
+			verb:        "get",
+			result:      openfgav1.ConsistencyPreference_MINIMIZE_LATENCY,
+		},
+		{
+			name:        "should accept lower case preferences",
+			preferences: map[string]string{"delete": "higher_consistency"},
+			verb:        "delete",
+			result:      openfgav1.ConsistencyPreference_HIGHER_CONSISTENCY,
+		},
+		{
+			name:        "should reject unknown preferences",
+			preferences: map[string]string{"get": "EVENTUAL"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := openfga.ParseConsistencyPolicy(tc.preferences)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, policy.For(tc.verb))
+		})
+	}
+
+	t.Run("zero value should leave the consistency unspecified", func(t *testing.T) {
+		assert.Equal(t, openfgav1.ConsistencyPreference_UNSPECIFIED, openfga.ConsistencyPolicy{}.For("get"))
+	})
+}
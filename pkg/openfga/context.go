@@ -0,0 +1,59 @@
+package openfga
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+// ContextBuilder builds the CheckRequest context that OpenFGA evaluates
+// conditions against. The context contains the parameters verb, resource,
+// subresource, namespace, name, api_group, cluster_name, account_name,
+// request_time and extra, holding the configured Extra keys of the request.
+type ContextBuilder struct {
+	extraKeys []string
+}
+
+// NewContextBuilder returns a ContextBuilder passing the given Extra keys.
+func NewContextBuilder(extraKeys ...string) *ContextBuilder {
+	return &ContextBuilder{extraKeys: extraKeys}
+}
+
+// Build returns the check context for a request against clusterName, which
+// belongs to accountName. It returns nil if b is nil.
+func (b *ContextBuilder) Build(spec authzv1.SubjectAccessReviewSpec, clusterName, accountName string) (*structpb.Struct, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	values := map[string]any{
+		"cluster_name": clusterName,
+		"account_name": accountName,
+		"request_time": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if attrs := spec.ResourceAttributes; attrs != nil {
+		values["verb"] = attrs.Verb
+		values["resource"] = attrs.Resource
+		values["subresource"] = attrs.Subresource
+		values["namespace"] = attrs.Namespace
+		values["name"] = attrs.Name
+		values["api_group"] = attrs.Group
+	}
+
+	extra := map[string]any{}
+	for _, key := range b.extraKeys {
+		if value, ok := spec.Extra[key]; ok {
+			list := make([]any, 0, len(value))
+			for _, v := range value {
+				list = append(list, v)
+			}
+			extra[key] = list
+		}
+	}
+	values["extra"] = extra
+
+	return structpb.NewStruct(values)
+}
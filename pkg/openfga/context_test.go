@@ -0,0 +1,57 @@
+package openfga_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+
+	authzv1 "k8s.io/api/authorization/v1"
+)
+
+func TestContextBuilder(t *testing.T) {
+	spec := authzv1.SubjectAccessReviewSpec{
+		User: "alice",
+		Extra: map[string]authzv1.ExtraValue{
+			"environment": {"prod"},
+			"secret":      {"hidden"},
+		},
+		ResourceAttributes: &authzv1.ResourceAttributes{
+			Verb:        "update",
+			Group:       "apps",
+			Resource:    "deployments",
+			Subresource: "scale",
+			Namespace:   "default",
+			Name:        "web",
+		},
+	}
+
+	t.Run("should pass request attributes", func(t *testing.T) {
+		ctx, err := openfga.NewContextBuilder("environment", "missing").Build(spec, "a", "origin-account")
+		assert.NoError(t, err)
+
+		values := ctx.AsMap()
+		assert.Equal(t, "update", values["verb"])
+		assert.Equal(t, "apps", values["api_group"])
+		assert.Equal(t, "deployments", values["resource"])
+		assert.Equal(t, "scale", values["subresource"])
+		assert.Equal(t, "default", values["namespace"])
+		assert.Equal(t, "web", values["name"])
+		assert.Equal(t, "a", values["cluster_name"])
+		assert.Equal(t, "origin-account", values["account_name"])
+		assert.Equal(t, map[string]any{"environment": []any{"prod"}}, values["extra"])
+
+		requestTime, err := time.Parse(time.RFC3339, values["request_time"].(string))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), requestTime, time.Minute)
+	})
+
+	t.Run("should not build a context without builder", func(t *testing.T) {
+		var builder *openfga.ContextBuilder
+
+		ctx, err := builder.Build(spec, "a", "origin-account")
+		assert.NoError(t, err)
+		assert.Nil(t, ctx)
+	})
+}
@@ -0,0 +1,26 @@
+package openfga
+
+import (
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ValidateDenyRelationFormat verifies that format derives a deny relation
+// from a checked relation, e.g. deny_%s.
+func ValidateDenyRelationFormat(format string) error {
+	if strings.Count(format, "%s") != 1 || strings.Count(format, "%") != 1 {
+		return fmt.Errorf("deny relation format %q must contain exactly one %%s", format)
+	}
+	return nil
+}
+
+// DenyCheck returns a copy of check asking for the deny relation derived
+// from its relation using format.
+func DenyCheck(check *openfgav1.CheckRequest, format string) *openfgav1.CheckRequest {
+	deny := proto.Clone(check).(*openfgav1.CheckRequest)
+	deny.TupleKey.Relation = fmt.Sprintf(format, check.TupleKey.Relation)
+	return deny
+}
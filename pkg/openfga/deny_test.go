@@ -0,0 +1,39 @@
+package openfga_test
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDenyRelationFormat(t *testing.T) {
+	assert.NoError(t, openfga.ValidateDenyRelationFormat("deny_%s"))
+	assert.Error(t, openfga.ValidateDenyRelationFormat("deny"))
+	assert.Error(t, openfga.ValidateDenyRelationFormat("deny_%s_%s"))
+	assert.Error(t, openfga.ValidateDenyRelationFormat("deny_%d"))
+}
+
+func TestDenyCheck(t *testing.T) {
+	check := &openfgav1.CheckRequest{
+		StoreId: "store-id",
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			Object:   "core_pod:a/web-0",
+			Relation: "get",
+			User:     "user:alice",
+		},
+		ContextualTuples: &openfgav1.ContextualTupleKeys{
+			TupleKeys: []*openfgav1.TupleKey{{Object: "core_pod:a/web-0", Relation: "parent", User: "core_namespace:a/default"}},
+		},
+	}
+
+	deny := openfga.DenyCheck(check, "deny_%s")
+
+	assert.Equal(t, "deny_get", deny.TupleKey.Relation)
+	assert.Equal(t, "core_pod:a/web-0", deny.TupleKey.Object)
+	assert.Equal(t, "user:alice", deny.TupleKey.User)
+	assert.Equal(t, "store-id", deny.StoreId)
+	assert.Len(t, deny.ContextualTuples.TupleKeys, 1)
+	assert.Equal(t, "get", check.TupleKey.Relation)
+}
@@ -0,0 +1,171 @@
+package openfga
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"k8s.io/klog/v2"
+)
+
+// AuthorizationModelIDAnnotation pins the authorization model of the OpenFGA
+// store backing a Store object.
+const AuthorizationModelIDAnnotation = "core.platform-mesh.io/authorization-model-id"
+
+// ModelProvider resolves the authorization model ID to send along with checks
+// against a store, and the types and relations defined by a model.
+type ModelProvider interface {
+	ModelID(ctx context.Context, storeID string) (string, error)
+	Model(ctx context.Context, storeID, modelID string) (*Model, error)
+}
+
+// Model describes the types and relations defined by an authorization model.
+type Model struct {
+	ID        string
+	relations map[string]map[string]bool
+}
+
+// NewModel indexes the type definitions of an authorization model.
+func NewModel(model *openfgav1.AuthorizationModel) *Model {
+	m := &Model{
+		ID:        model.GetId(),
+		relations: make(map[string]map[string]bool, len(model.GetTypeDefinitions())),
+	}
+	for _, td := range model.GetTypeDefinitions() {
+		relations := make(map[string]bool, len(td.GetRelations()))
+		for relation := range td.GetRelations() {
+			relations[relation] = true
+		}
+		m.relations[td.GetType()] = relations
+	}
+	return m
+}
+
+// HasType reports whether the model defines objectType.
+func (m *Model) HasType(objectType string) bool {
+	_, ok := m.relations[objectType]
+	return ok
+}
+
+// HasRelation reports whether the model defines relation on objectType.
+func (m *Model) HasRelation(objectType, relation string) bool {
+	return m.relations[objectType][relation]
+}
+
+// ObjectType returns the type of an FGA object like "core_namespace:cluster/name".
+func ObjectType(object string) string {
+	objectType, _, _ := strings.Cut(object, ":")
+	return objectType
+}
+
+type cachedModel struct {
+	id        string
+	fetchedAt time.Time
+}
+
+// ModelCache resolves the latest authorization model of a store and keeps
+// using it for a refresh interval, so that all checks against a store agree on
+// the model while an update is being rolled out.
+type ModelCache struct {
+	fga             openfgav1.OpenFGAServiceClient
+	refreshInterval time.Duration
+	pinned          map[string]string
+
+	lock   sync.RWMutex
+	latest map[string]cachedModel
+	// models holds models by ID. Models are immutable, so they never expire.
+	models map[string]*Model
+}
+
+var _ ModelProvider = &ModelCache{}
+
+// NewModelCache returns a ModelCache refreshing models every refreshInterval.
+// Stores listed in pinned always use the given model ID.
+func NewModelCache(fga openfgav1.OpenFGAServiceClient, refreshInterval time.Duration, pinned map[string]string) *ModelCache {
+	return &ModelCache{
+		fga:             fga,
+		refreshInterval: refreshInterval,
+		pinned:          pinned,
+		latest:          make(map[string]cachedModel),
+		models:          make(map[string]*Model),
+	}
+}
+
+// ModelID implements ModelProvider.
+func (m *ModelCache) ModelID(ctx context.Context, storeID string) (string, error) {
+	if modelID, ok := m.pinned[storeID]; ok {
+		return modelID, nil
+	}
+
+	m.lock.RLock()
+	cached, ok := m.latest[storeID]
+	m.lock.RUnlock()
+	if ok && time.Since(cached.fetchedAt) < m.refreshInterval {
+		return cached.id, nil
+	}
+
+	res, err := m.fga.ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{
+		StoreId:  storeID,
+		PageSize: wrapperspb.Int32(1),
+	})
+	if err == nil && len(res.AuthorizationModels) == 0 {
+		err = fmt.Errorf("no authorization model found in store %q", storeID)
+	}
+	if err != nil {
+		if ok {
+			// keep checking against the previous model until OpenFGA recovers
+			klog.ErrorS(err, "failed to refresh authorization model, using previous model", "storeID", storeID, "modelID", cached.id)
+			return cached.id, nil
+		}
+		return "", err
+	}
+
+	model := NewModel(res.AuthorizationModels[0])
+	modelID := model.ID
+	if ok && cached.id != modelID {
+		klog.InfoS("authorization model changed", "storeID", storeID, "previousModelID", cached.id, "modelID", modelID)
+	}
+
+	m.lock.Lock()
+	m.latest[storeID] = cachedModel{id: modelID, fetchedAt: time.Now()}
+	m.models[modelID] = model
+	m.lock.Unlock()
+
+	klog.V(5).InfoS("resolved authorization model", "storeID", storeID, "modelID", modelID)
+	return modelID, nil
+}
+
+// Model implements ModelProvider.
+func (m *ModelCache) Model(ctx context.Context, storeID, modelID string) (*Model, error) {
+	m.lock.RLock()
+	model, ok := m.models[modelID]
+	m.lock.RUnlock()
+	if ok {
+		return model, nil
+	}
+
+	res, err := m.fga.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+		StoreId: storeID,
+		Id:      modelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.AuthorizationModel == nil {
+		return nil, fmt.Errorf("authorization model %q not found in store %q", modelID, storeID)
+	}
+
+	model = NewModel(res.AuthorizationModel)
+
+	m.lock.Lock()
+	m.models[modelID] = model
+	m.lock.Unlock()
+
+	klog.V(5).InfoS("loaded authorization model", "storeID", storeID, "modelID", modelID)
+	return model, nil
+}
@@ -0,0 +1,181 @@
+package openfga_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestModelCache(t *testing.T) {
+	readModels := func(ids ...string) *openfgav1.ReadAuthorizationModelsResponse {
+		res := &openfgav1.ReadAuthorizationModelsResponse{}
+		for _, id := range ids {
+			res.AuthorizationModels = append(res.AuthorizationModels, &openfgav1.AuthorizationModel{Id: id})
+		}
+		return res
+	}
+
+	t.Run("should resolve the latest model once per refresh interval", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.MatchedBy(func(in *openfgav1.ReadAuthorizationModelsRequest) bool {
+			return in.StoreId == "store-id" && in.PageSize.GetValue() == 1
+		})).Return(readModels("model-1"), nil).Once()
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		for range 3 {
+			id, err := models.ModelID(t.Context(), "store-id")
+			assert.NoError(t, err)
+			assert.Equal(t, "model-1", id)
+		}
+	})
+
+	t.Run("should pick up a new model after the refresh interval", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModels("model-1"), nil).Once()
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModels("model-2", "model-1"), nil).Once()
+
+		models := openfga.NewModelCache(fga, 0, nil)
+
+		id, err := models.ModelID(t.Context(), "store-id")
+		assert.NoError(t, err)
+		assert.Equal(t, "model-1", id)
+
+		id, err = models.ModelID(t.Context(), "store-id")
+		assert.NoError(t, err)
+		assert.Equal(t, "model-2", id)
+	})
+
+	t.Run("should keep the previous model if the refresh fails", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModels("model-1"), nil).Once()
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(nil, errors.New("unavailable")).Once()
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModels(), nil).Once()
+
+		models := openfga.NewModelCache(fga, 0, nil)
+
+		for range 3 {
+			id, err := models.ModelID(t.Context(), "store-id")
+			assert.NoError(t, err)
+			assert.Equal(t, "model-1", id)
+		}
+	})
+
+	t.Run("should use pinned model without reading models", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+
+		models := openfga.NewModelCache(fga, time.Hour, map[string]string{"store-id": "pinned"})
+
+		id, err := models.ModelID(t.Context(), "store-id")
+		assert.NoError(t, err)
+		assert.Equal(t, "pinned", id)
+	})
+
+	t.Run("should fail if the store has no model", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModels(), nil)
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		_, err := models.ModelID(t.Context(), "store-id")
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail if models cannot be read", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		_, err := models.ModelID(t.Context(), "store-id")
+		assert.Error(t, err)
+	})
+}
+
+func TestModelCache_Model(t *testing.T) {
+	authorizationModel := &openfgav1.AuthorizationModel{
+		Id: "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{Type: "core_namespace", Relations: map[string]*openfgav1.Userset{"parent": {}, "get": {}}},
+		},
+	}
+
+	t.Run("should load and cache a model by ID", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(in *openfgav1.ReadAuthorizationModelRequest) bool {
+			return in.StoreId == "store-id" && in.Id == "model-1"
+		})).Return(&openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: authorizationModel}, nil).Once()
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		for range 2 {
+			model, err := models.Model(t.Context(), "store-id", "model-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "model-1", model.ID)
+		}
+	})
+
+	t.Run("should reuse the model read while resolving the latest model", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{authorizationModel},
+		}, nil)
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		id, err := models.ModelID(t.Context(), "store-id")
+		assert.NoError(t, err)
+
+		model, err := models.Model(t.Context(), "store-id", id)
+		assert.NoError(t, err)
+		assert.True(t, model.HasRelation("core_namespace", "get"))
+	})
+
+	t.Run("should fail if the model cannot be read", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		_, err := models.Model(t.Context(), "store-id", "model-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail if the model does not exist", func(t *testing.T) {
+		fga := mocks.NewOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{}, nil)
+
+		models := openfga.NewModelCache(fga, time.Hour, nil)
+
+		_, err := models.Model(t.Context(), "store-id", "model-1")
+		assert.Error(t, err)
+	})
+}
+
+func TestModel(t *testing.T) {
+	model := openfga.NewModel(&openfgav1.AuthorizationModel{
+		Id: "model-1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{Type: "core_namespace", Relations: map[string]*openfgav1.Userset{"parent": {}, "get": {}}},
+		},
+	})
+
+	assert.True(t, model.HasType("user"))
+	assert.True(t, model.HasType("core_namespace"))
+	assert.False(t, model.HasType("core_pod"))
+
+	assert.True(t, model.HasRelation("core_namespace", "get"))
+	assert.False(t, model.HasRelation("core_namespace", "delete"))
+	assert.False(t, model.HasRelation("core_pod", "get"))
+
+	assert.Equal(t, "core_namespace", openfga.ObjectType("core_namespace:cluster/name"))
+	assert.Equal(t, "user", openfga.ObjectType("user"))
+}
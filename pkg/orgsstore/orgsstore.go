@@ -0,0 +1,138 @@
+package orgsstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/openfga"
+)
+
+var storeGVK = schema.GroupVersionKind{
+	Group:   "core.platform-mesh.io",
+	Version: "v1alpha1",
+	Kind:    "Store",
+}
+
+// Provider yields the ID of the OpenFGA store backing the orgs workspace.
+type Provider interface {
+	// StoreID returns the current store ID and whether it has been resolved yet.
+	StoreID() (string, bool)
+	// AuthorizationModelID returns the model pinned on the Store object, if any.
+	AuthorizationModelID() string
+}
+
+// Watcher resolves the orgs store ID from the status of a Store object and
+// keeps it up to date, so that a recreated store is picked up without a restart.
+type Watcher struct {
+	lock                 sync.RWMutex
+	storeID              string
+	authorizationModelID string
+
+	mgr         mcmanager.Manager
+	clusterName multicluster.ClusterName
+	storeName   string
+	interval    time.Duration
+}
+
+var _ Provider = &Watcher{}
+var _ mcmanager.Runnable = &Watcher{}
+
+// New returns a Watcher reading the Store named storeName in clusterName every interval.
+func New(mgr mcmanager.Manager, clusterName, storeName string, interval time.Duration) *Watcher {
+	return &Watcher{
+		mgr:         mgr,
+		clusterName: multicluster.ClusterName(clusterName),
+		storeName:   storeName,
+		interval:    interval,
+	}
+}
+
+// StoreID implements Provider.
+func (w *Watcher) StoreID() (string, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.storeID, w.storeID != ""
+}
+
+// AuthorizationModelID implements Provider.
+func (w *Watcher) AuthorizationModelID() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.authorizationModelID
+}
+
+// Start periodically resolves the store ID until ctx is cancelled.
+func (w *Watcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, w.sync, w.interval)
+	return nil
+}
+
+// Engage implements multicluster.Aware. The watcher looks up its cluster by
+// name on every sync, so there is nothing to do here.
+func (w *Watcher) Engage(_ context.Context, _ multicluster.ClusterName, _ cluster.Cluster) error { // coverage-ignore
+	return nil
+}
+
+func (w *Watcher) sync(ctx context.Context) {
+	storeID, authorizationModelID, err := w.resolve(ctx)
+	if err != nil {
+		if _, ok := w.StoreID(); !ok {
+			// requests to the orgs workspace get no opinion until resolved
+			klog.ErrorS(err, "Failed to resolve orgs store, will retry", "clusterName", w.clusterName, "storeName", w.storeName)
+			metrics.RecordOrgsStoreResolved(false)
+			return
+		}
+		klog.V(5).ErrorS(err, "Failed to resync orgs store, keeping the previous one", "clusterName", w.clusterName, "storeName", w.storeName)
+		return
+	}
+
+	w.lock.Lock()
+	previous := w.storeID
+	w.storeID = storeID
+	w.authorizationModelID = authorizationModelID
+	w.lock.Unlock()
+	metrics.RecordOrgsStoreResolved(true)
+
+	switch {
+	case previous == "":
+		klog.InfoS("using OpenFGA orgs store", "id", storeID)
+	case previous != storeID:
+		klog.InfoS("OpenFGA orgs store changed", "previousID", previous, "id", storeID)
+	}
+}
+
+func (w *Watcher) resolve(ctx context.Context) (string, string, error) {
+	cl, err := w.mgr.GetCluster(ctx, w.clusterName)
+	if err != nil {
+		return "", "", err
+	}
+
+	store := unstructured.Unstructured{}
+	store.SetGroupVersionKind(storeGVK)
+	if err := cl.GetClient().Get(ctx, types.NamespacedName{Name: w.storeName}, &store); err != nil {
+		return "", "", err
+	}
+
+	storeID, found, err := unstructured.NestedString(store.Object, "status", "storeId")
+	if err != nil {
+		return "", "", err
+	}
+	if !found || storeID == "" {
+		return "", "", errors.New("storeId not found in Store status")
+	}
+
+	return storeID, store.GetAnnotations()[openfga.AuthorizationModelIDAnnotation], nil
+}
@@ -0,0 +1,116 @@
+package orgsstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/handler/mocks"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/metrics"
+	"github.com/platform-mesh/rebac-authz-webhook/pkg/orgsstore"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+)
+
+func TestWatcher(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setupMocks  func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client)
+		wantStoreID string
+	}{
+		{
+			name: "should resolve store ID from Store status",
+			setupMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(cluster, nil)
+				cluster.EXPECT().GetClient().Return(orgsClient)
+				setupStoreGet(orgsClient, map[string]any{"storeId": "store-1"}).Maybe()
+			},
+			wantStoreID: "store-1",
+		},
+		{
+			name: "should follow store rotation",
+			setupMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(cluster, nil)
+				cluster.EXPECT().GetClient().Return(orgsClient)
+				setupStoreGet(orgsClient, map[string]any{"storeId": "store-1"}).Once()
+				setupStoreGet(orgsClient, map[string]any{"storeId": "store-2"}).Maybe()
+			},
+			wantStoreID: "store-2",
+		},
+		{
+			name: "should stay unresolved if cluster is not available",
+			setupMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(nil, errors.New("not found"))
+			},
+		},
+		{
+			name: "should stay unresolved if Store has no storeId",
+			setupMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(cluster, nil)
+				cluster.EXPECT().GetClient().Return(orgsClient)
+				setupStoreGet(orgsClient, map[string]any{})
+			},
+		},
+		{
+			name: "should stay unresolved if Store cannot be read",
+			setupMocks: func(mgr *mocks.Manager, cluster *mocks.Cluster, orgsClient *mocks.Client) {
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root:orgs")).Return(cluster, nil)
+				cluster.EXPECT().GetClient().Return(orgsClient)
+				orgsClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "orgs"}, mock.Anything).Return(errors.New("forbidden"))
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mgr := mocks.NewManager(t)
+			cluster := mocks.NewCluster(t)
+			orgsClient := mocks.NewClient(t)
+			test.setupMocks(mgr, cluster, orgsClient)
+
+			w := orgsstore.New(mgr, "root:orgs", "orgs", 10*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				assert.NoError(t, w.Start(ctx))
+			}()
+
+			if test.wantStoreID == "" {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+				<-done
+
+				_, ok := w.StoreID()
+				assert.False(t, ok)
+				assert.Equal(t, 0.0, testutil.ToFloat64(metrics.OrgsStoreResolved))
+				return
+			}
+
+			assert.Eventually(t, func() bool {
+				id, _ := w.StoreID()
+				return id == test.wantStoreID
+			}, time.Second, 5*time.Millisecond)
+			cancel()
+			<-done
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrgsStoreResolved))
+		})
+	}
+}
+
+func setupStoreGet(c *mocks.Client, status map[string]any) *mocks.Client_Get_Call {
+	return c.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "orgs"}, mock.Anything).
+		Run(func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) {
+			obj.(*unstructured.Unstructured).Object["status"] = status
+		}).
+		Return(nil)
+}
@@ -6,13 +6,23 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func ResolveOnParent(verb string) bool {
-	return verb == "create" || verb == "list" || verb == "watch"
+// LongestVerb is the longest verb checked on relations derived from a
+// resource, e.g. deletecollection_<group>_<resource>. Only
+// TypeNamingTruncateAllVerbs shortens groups so that its relations fit the
+// relation length limit.
+const LongestVerb = "deletecollection"
+
+// CapGroupToRelationLength cuts the group of gvr from the left so that
+// create_<group>_<resource> fits maxLength.
+func CapGroupToRelationLength(gvr schema.GroupVersionResource, maxLength int) string {
+	return capGroup(gvr, maxLength, "create")
 }
 
-func CapGroupToRelationLength(gvr schema.GroupVersionResource, maxLength int) string {
+// capGroup cuts the group of gvr from the left so that relations of verb fit
+// maxLength.
+func capGroup(gvr schema.GroupVersionResource, maxLength int, verb string) string {
 
-	maxRelation := fmt.Sprintf("create_%s_%s", gvr.Group, gvr.Resource)
+	maxRelation := fmt.Sprintf("%s_%s_%s", verb, gvr.Group, gvr.Resource)
 
 	group := gvr.Group
 	if group == "" {
@@ -27,19 +27,24 @@ func FuzzCapGroupToRelationLength(f *testing.F) {
 
 		// Must not panic
 		_ = CapGroupToRelationLength(gvr, maxLength)
+		_, _ = TypeNamingHashed.ObjectType(gvr, resource, maxLength)
 	})
 }
 
-func FuzzResolveOnParent(f *testing.F) {
-	f.Add("create")
-	f.Add("list")
-	f.Add("watch")
-	f.Add("get")
-	f.Add("delete")
-	f.Add("")
+func FuzzHashedGroupFitsRelationLength(f *testing.F) {
+	f.Add("apps", "deployments")
+	f.Add("very.long.group.name.example.io", "verylongresourcename")
+	f.Add("alpha.monitoring.platform-mesh.io", "servicemonitorconfigurations")
 
-	f.Fuzz(func(t *testing.T, verb string) {
-		// Must not panic
-		_ = ResolveOnParent(verb)
+	f.Fuzz(func(t *testing.T, group, resource string) {
+		gvr := schema.GroupVersionResource{Group: group, Resource: resource}
+
+		hashed := TypeNamingHashed.Group(gvr, MaxRelationLength)
+		if len(resource) > MaxRelationLength-len("create__")-groupHashLength {
+			return
+		}
+		if relation := "create_" + hashed + "_" + resource; len(relation) > MaxRelationLength {
+			t.Errorf("relation %q exceeds %d characters", relation, MaxRelationLength)
+		}
 	})
 }
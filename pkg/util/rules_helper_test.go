@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCapGroupToRelationLength(t *testing.T) {
+	testCases := []struct {
+		name  string
+		gvr   schema.GroupVersionResource
+		group string
+	}{
+		{
+			name:  "should keep short groups",
+			gvr:   schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			group: "apps",
+		},
+		{
+			name:  "should use core for the core group",
+			gvr:   schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			group: "core",
+		},
+		{
+			name:  "should keep groups fitting create relations",
+			gvr:   schema.GroupVersionResource{Group: "core.platform-mesh.io", Version: "v1alpha1", Resource: "accountinfos"},
+			group: "core.platform-mesh.io",
+		},
+		{
+			name:  "should cut long groups from the left",
+			gvr:   schema.GroupVersionResource{Group: "alpha.monitoring.platform-mesh.io", Version: "v1", Resource: "servicemonitorconfigurations"},
+			group: "atform-mesh.io",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			group := CapGroupToRelationLength(tc.gvr, MaxRelationLength)
+			assert.Equal(t, tc.group, group)
+			assert.LessOrEqual(t, len("create_"+group+"_"+tc.gvr.Resource), MaxRelationLength)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package util
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TypeNaming configures how groups too long for the relation length limit
+// are shortened in relations and object types.
+type TypeNaming string
+
+const (
+	// TypeNamingTruncate cuts long groups from the left. Groups sharing the
+	// same tail map to the same object type.
+	TypeNamingTruncate TypeNaming = "truncate"
+	// TypeNamingHashed keeps the tail of long groups followed by a short
+	// hash of the full group.
+	TypeNamingHashed TypeNaming = "hashed"
+	// TypeNamingTruncateAllVerbs cuts long groups from the left like
+	// TypeNamingTruncate, but far enough that relations of the longest verb,
+	// deletecollection, fit as well. Groups fitting create but not
+	// deletecollection relations are named differently than with
+	// TypeNamingTruncate.
+	TypeNamingTruncateAllVerbs TypeNaming = "truncate-all-verbs"
+)
+
+// MaxRelationLength is the longest relation name derived from a resource.
+const MaxRelationLength = 50
+
+// groupHashLength is the number of hex characters of the group hash.
+const groupHashLength = 8
+
+// ParseTypeNaming validates naming.
+func ParseTypeNaming(naming string) (TypeNaming, error) {
+	switch n := TypeNaming(naming); n {
+	case TypeNamingTruncate, TypeNamingHashed, TypeNamingTruncateAllVerbs:
+		return n, nil
+	default:
+		return "", fmt.Errorf("unknown type naming %q, expected %q, %q or %q", naming, TypeNamingTruncate, TypeNamingHashed, TypeNamingTruncateAllVerbs)
+	}
+}
+
+// sizingVerb returns the verb whose relations groups are shortened to fit.
+func (n TypeNaming) sizingVerb() string {
+	if n == TypeNamingTruncateAllVerbs {
+		return LongestVerb
+	}
+	return "create"
+}
+
+// Group returns the group of gvr as used in relations like
+// create_<group>_<resource>, shortened to fit maxLength.
+func (n TypeNaming) Group(gvr schema.GroupVersionResource, maxLength int) string {
+	if n != TypeNamingHashed {
+		return capGroup(gvr, maxLength, n.sizingVerb())
+	}
+
+	group := gvr.Group
+	if group == "" {
+		group = "core"
+	}
+
+	if len(fmt.Sprintf("create_%s_%s", group, gvr.Resource)) <= maxLength {
+		return group
+	}
+
+	hash := groupHash(gvr.Group)
+	tailLength := maxLength - len(fmt.Sprintf("create_.%s_%s", hash, gvr.Resource))
+	if tailLength <= 0 {
+		return hash
+	}
+
+	tail := strings.TrimLeft(group[len(group)-tailLength:], ".")
+	if tail == "" {
+		return hash
+	}
+	return fmt.Sprintf("%s.%s", tail, hash)
+}
+
+// ObjectType returns the relation group and the OpenFGA object type of gvr,
+// e.g. apps and apps_deployment.
+func (n TypeNaming) ObjectType(gvr schema.GroupVersionResource, singular string, maxLength int) (string, string) {
+	group := n.Group(gvr, maxLength)
+	group = strings.ReplaceAll(group, ".", "_")
+
+	objectType := fmt.Sprintf("%s_%s", group, singular)
+	longestObjectType := fmt.Sprintf("%s_%ss", n.sizingVerb(), objectType)
+	if len(longestObjectType) > maxLength {
+		objectType = objectType[min(len(longestObjectType)-maxLength, len(objectType)):]
+	}
+
+	return group, objectType
+}
+
+func groupHash(group string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(group))
+	return fmt.Sprintf("%0*x", groupHashLength, h.Sum32())
+}
+
+// TypeCollision reports two group resources sharing an OpenFGA object type.
+type TypeCollision struct {
+	ObjectType string
+	Existing   schema.GroupResource
+	Colliding  schema.GroupResource
+}
+
+// TypeRegistry records which group resource each object type was derived
+// from and detects when different group resources share an object type.
+type TypeRegistry struct {
+	lock      sync.Mutex
+	naming    TypeNaming
+	maxLength int
+	types     map[string]schema.GroupResource
+	// reported holds the group resources whose collision was returned.
+	reported map[schema.GroupResource]bool
+}
+
+// NewTypeRegistry returns an empty registry deriving object types with naming.
+func NewTypeRegistry(naming TypeNaming, maxLength int) *TypeRegistry {
+	return &TypeRegistry{
+		naming:    naming,
+		maxLength: maxLength,
+		types:     map[string]schema.GroupResource{},
+		reported:  map[schema.GroupResource]bool{},
+	}
+}
+
+// Register records the object type of gvr and returns the collision with a
+// previously registered group resource, if any. Each collision is only
+// returned the first time the colliding group resource is registered.
+func (r *TypeRegistry) Register(gvr schema.GroupVersionResource, singular string) (TypeCollision, bool) {
+	_, objectType := r.naming.ObjectType(gvr, singular, r.maxLength)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	gr := gvr.GroupResource()
+	existing, ok := r.types[objectType]
+	if !ok {
+		r.types[objectType] = gr
+		return TypeCollision{}, false
+	}
+	if existing == gr || r.reported[gr] {
+		return TypeCollision{}, false
+	}
+	r.reported[gr] = true
+	return TypeCollision{ObjectType: objectType, Existing: existing, Colliding: gr}, true
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	alphaMonitors = schema.GroupVersionResource{Group: "alpha.monitoring.platform-mesh.io", Version: "v1", Resource: "servicemonitorconfigurations"}
+	betaMonitors  = schema.GroupVersionResource{Group: "beta.monitoring.platform-mesh.io", Version: "v1", Resource: "servicemonitorconfigurations"}
+)
+
+func TestTypeNaming_ObjectType(t *testing.T) {
+	testCases := []struct {
+		name       string
+		naming     TypeNaming
+		gvr        schema.GroupVersionResource
+		singular   string
+		group      string
+		objectType string
+	}{
+		{
+			name:       "should keep short groups with truncate naming",
+			naming:     TypeNamingTruncate,
+			gvr:        schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			singular:   "deployment",
+			group:      "apps",
+			objectType: "apps_deployment",
+		},
+		{
+			name:       "should keep short groups with hashed naming",
+			naming:     TypeNamingHashed,
+			gvr:        schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			singular:   "deployment",
+			group:      "apps",
+			objectType: "apps_deployment",
+		},
+		{
+			name:       "should use core for the core group with hashed naming",
+			naming:     TypeNamingHashed,
+			gvr:        schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			singular:   "pod",
+			group:      "core",
+			objectType: "core_pod",
+		},
+		{
+			name:       "should truncate long groups from the left",
+			naming:     TypeNamingTruncate,
+			gvr:        alphaMonitors,
+			singular:   "servicemonitorconfiguration",
+			group:      "atform-mesh_io",
+			objectType: "atform-mesh_io_servicemonitorconfiguration",
+		},
+		{
+			name:       "should append a hash of the full group to long groups",
+			naming:     TypeNamingHashed,
+			gvr:        alphaMonitors,
+			singular:   "servicemonitorconfiguration",
+			group:      "sh_io_" + groupHash(alphaMonitors.Group),
+			objectType: "sh_io_" + groupHash(alphaMonitors.Group) + "_servicemonitorconfiguration",
+		},
+		{
+			name:       "should keep groups fitting create relations with truncate naming",
+			naming:     TypeNamingTruncate,
+			gvr:        schema.GroupVersionResource{Group: "core.platform-mesh.io", Version: "v1alpha1", Resource: "accountinfos"},
+			singular:   "accountinfo",
+			group:      "core_platform-mesh_io",
+			objectType: "core_platform-mesh_io_accountinfo",
+		},
+		{
+			name:       "should fit deletecollection relations with truncate-all-verbs naming",
+			naming:     TypeNamingTruncateAllVerbs,
+			gvr:        schema.GroupVersionResource{Group: "core.platform-mesh.io", Version: "v1alpha1", Resource: "accountinfos"},
+			singular:   "accountinfo",
+			group:      "ore_platform-mesh_io",
+			objectType: "ore_platform-mesh_io_accountinfo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			group, objectType := tc.naming.ObjectType(tc.gvr, tc.singular, MaxRelationLength)
+			assert.Equal(t, tc.group, group)
+			assert.Equal(t, tc.objectType, objectType)
+			assert.LessOrEqual(t, len(tc.naming.sizingVerb()+"_"+group+"_"+tc.gvr.Resource), MaxRelationLength)
+		})
+	}
+}
+
+func TestTypeNaming_HashedIsStable(t *testing.T) {
+	assert.Equal(t, TypeNamingHashed.Group(alphaMonitors, MaxRelationLength), TypeNamingHashed.Group(alphaMonitors, MaxRelationLength))
+	assert.NotEqual(t, TypeNamingHashed.Group(alphaMonitors, MaxRelationLength), TypeNamingHashed.Group(betaMonitors, MaxRelationLength))
+	assert.Equal(t, TypeNamingTruncate.Group(alphaMonitors, MaxRelationLength), TypeNamingTruncate.Group(betaMonitors, MaxRelationLength))
+}
+
+func TestParseTypeNaming(t *testing.T) {
+	naming, err := ParseTypeNaming("hashed")
+	assert.NoError(t, err)
+	assert.Equal(t, TypeNamingHashed, naming)
+
+	naming, err = ParseTypeNaming("truncate-all-verbs")
+	assert.NoError(t, err)
+	assert.Equal(t, TypeNamingTruncateAllVerbs, naming)
+
+	_, err = ParseTypeNaming("sha256")
+	assert.Error(t, err)
+}
+
+func TestTypeRegistry_Register(t *testing.T) {
+	registry := NewTypeRegistry(TypeNamingTruncate, MaxRelationLength)
+
+	_, collides := registry.Register(alphaMonitors, "servicemonitorconfiguration")
+	assert.False(t, collides)
+
+	// other versions of the same resource do not collide
+	_, collides = registry.Register(alphaMonitors.GroupResource().WithVersion("v2"), "servicemonitorconfiguration")
+	assert.False(t, collides)
+
+	collision, collides := registry.Register(betaMonitors, "servicemonitorconfiguration")
+	assert.True(t, collides)
+	assert.Equal(t, TypeCollision{
+		ObjectType: "atform-mesh_io_servicemonitorconfiguration",
+		Existing:   alphaMonitors.GroupResource(),
+		Colliding:  betaMonitors.GroupResource(),
+	}, collision)
+
+	// collisions are only returned once
+	_, collides = registry.Register(betaMonitors, "servicemonitorconfiguration")
+	assert.False(t, collides)
+
+	hashed := NewTypeRegistry(TypeNamingHashed, MaxRelationLength)
+	_, collides = hashed.Register(alphaMonitors, "servicemonitorconfiguration")
+	assert.False(t, collides)
+	_, collides = hashed.Register(betaMonitors, "servicemonitorconfiguration")
+	assert.False(t, collides, "hashed names of different groups do not collide")
+}